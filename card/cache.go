@@ -0,0 +1,61 @@
+package card
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache keeps the most recently used rendered cards in memory. It is safe
+// for concurrent use.
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // Front is the most recently used
+	entries    map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  string
+	data []byte
+}
+
+// NewCache returns a cache holding up to maxEntries cards
+func NewCache(maxEntries int) *Cache {
+	return &Cache{maxEntries: maxEntries, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Get returns the cached card for key
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).data, true
+}
+
+// Add caches a card, evicting the least recently used one when full
+func (c *Cache) Add(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).data = data
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, data})
+	if c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Len returns the number of cached cards
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,315 @@
+// Package card renders quotes as shareable image cards, as PNG with the
+// embedded Go fonts or as SVG laid out with the same font metrics.
+package card
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Theme is the color scheme of a card
+type Theme struct {
+	Background color.RGBA
+	Text       color.RGBA
+	Accent     color.RGBA
+}
+
+// Size is the pixel size of a card
+type Size struct {
+	Width  int
+	Height int
+}
+
+// Themes and sizes cards can be rendered with
+var (
+	Themes = map[string]Theme{
+		"light": {rgb(0xfa, 0xfa, 0xf7), rgb(0x1f, 0x23, 0x28), rgb(0xd9, 0x48, 0x2b)},
+		"dark":  {rgb(0x16, 0x1b, 0x22), rgb(0xf0, 0xf3, 0xf6), rgb(0x58, 0xa6, 0xff)},
+		"sepia": {rgb(0xf4, 0xec, 0xd8), rgb(0x43, 0x34, 0x22), rgb(0x9c, 0x6b, 0x30)},
+	}
+	Sizes = map[string]Size{
+		"landscape": {1200, 630}, // Open Graph and Twitter cards
+		"square":    {1080, 1080},
+		"portrait":  {1080, 1350},
+	}
+)
+
+// Defaults used when no theme or size is requested
+const (
+	DefaultTheme = "light"
+	DefaultSize  = "landscape"
+)
+
+// Card is a quote to render
+type Card struct {
+	Text   string
+	Author string
+	Theme  Theme
+	Size   Size
+}
+
+// Names returns the sorted keys of Themes or Sizes, for error messages
+func Names[T any](options map[string]T) []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func rgb(r, g, b uint8) color.RGBA {
+	return color.RGBA{r, g, b, 0xff}
+}
+
+var (
+	fontsOnce             sync.Once
+	regularFont, boldFont *opentype.Font
+	fontsErr              error
+)
+
+// loadFonts parses the embedded fonts once
+func loadFonts() error {
+	fontsOnce.Do(func() {
+		if regularFont, fontsErr = opentype.Parse(goregular.TTF); fontsErr != nil {
+			return
+		}
+		boldFont, fontsErr = opentype.Parse(gobold.TTF)
+	})
+	return fontsErr
+}
+
+func newFace(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// layout is where the parts of a card go, in pixels
+type layout struct {
+	padding    int
+	lines      []string
+	textSize   float64
+	lineHeight int
+	textTop    int // Baseline of the first line
+	authorSize float64
+	author     string
+	authorTop  int // Baseline of the author line
+	ruleTop    int
+	ruleWidth  int
+	ruleHeight int
+}
+
+// Limits of the quote text size, relative to the card height
+const (
+	maxTextRatio = 0.11
+	minTextRatio = 0.035
+	lineSpacing  = 1.3
+)
+
+// layOut wraps the text at the largest size that fits the card, down to a
+// minimum size below which the text is cut short with an ellipsis
+func layOut(card Card) (layout, error) {
+	if err := loadFonts(); err != nil {
+		return layout{}, err
+	}
+	w, h := card.Size.Width, card.Size.Height
+	l := layout{padding: min(w, h) * 8 / 100}
+	l.authorSize = float64(h) * 0.045
+	l.ruleHeight = max(int(l.authorSize/8), 2)
+	l.ruleWidth = l.padding
+	authorBlock := int(l.authorSize*lineSpacing) + l.ruleHeight + int(l.authorSize)
+
+	textWidth := w - 2*l.padding
+	textHeight := h - 2*l.padding - authorBlock
+	text := "“" + strings.Join(strings.Fields(printable(card.Text)), " ") + "”"
+
+	for size := float64(h) * maxTextRatio; ; size *= 0.92 {
+		face, err := newFace(regularFont, size)
+		if err != nil {
+			return layout{}, err
+		}
+		lineHeight := int(size * lineSpacing)
+		lines := wrap(face, text, textWidth)
+		fits := len(lines)*lineHeight <= textHeight
+		last := size*0.92 < float64(h)*minTextRatio
+		if fits || last {
+			if !fits {
+				lines = lines[:max(textHeight/lineHeight, 1)]
+				lines[len(lines)-1] = ellipsize(face, lines[len(lines)-1], textWidth)
+			}
+			l.lines, l.textSize, l.lineHeight = lines, size, lineHeight
+			face.Close()
+			break
+		}
+		face.Close()
+	}
+
+	// Center the text and author block vertically
+	blockHeight := len(l.lines)*l.lineHeight + authorBlock
+	top := (h - blockHeight) / 2
+	l.textTop = top + int(l.textSize)
+	l.ruleTop = top + len(l.lines)*l.lineHeight + int(l.authorSize/2)
+	l.authorTop = l.ruleTop + l.ruleHeight + int(l.authorSize*lineSpacing)
+
+	authorFace, err := newFace(boldFont, l.authorSize)
+	if err != nil {
+		return layout{}, err
+	}
+	defer authorFace.Close()
+	l.author = "— " + strings.Join(strings.Fields(printable(card.Author)), " ")
+	if font.MeasureString(authorFace, l.author).Ceil() > textWidth {
+		l.author = ellipsize(authorFace, l.author, textWidth)
+	}
+	return l, nil
+}
+
+// printable drops control characters, which fonts cannot draw and SVG
+// cannot contain
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// wrap breaks text into lines no wider than width, splitting words that are
+// too long on their own
+func wrap(face font.Face, text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if font.MeasureString(face, candidate).Ceil() <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		for font.MeasureString(face, word).Ceil() > width {
+			cut := fit(face, word, width)
+			lines = append(lines, word[:cut])
+			word = word[cut:]
+		}
+		line = word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fit returns the byte length of the longest prefix of s no wider than
+// width, at least one rune
+func fit(face font.Face, s string, width int) int {
+	end := 0
+	for i, r := range s {
+		next := i + utf8.RuneLen(r)
+		if end > 0 && font.MeasureString(face, s[:next]).Ceil() > width {
+			break
+		}
+		end = next
+	}
+	return end
+}
+
+// ellipsize shortens s to fit width, ending it with an ellipsis
+func ellipsize(face font.Face, s string, width int) string {
+	for s != "" && font.MeasureString(face, s+"…").Ceil() > width {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return strings.TrimRight(s, " ") + "…"
+}
+
+// PNG renders a card as a PNG image
+func PNG(w io.Writer, card Card) error {
+	l, err := layOut(card)
+	if err != nil {
+		return err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, card.Size.Width, card.Size.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(card.Theme.Background), image.Point{}, draw.Src)
+
+	textFace, err := newFace(regularFont, l.textSize)
+	if err != nil {
+		return err
+	}
+	defer textFace.Close()
+	drawer := font.Drawer{Dst: img, Src: image.NewUniform(card.Theme.Text), Face: textFace}
+	for i, line := range l.lines {
+		drawer.Dot = fixed.P(l.padding, l.textTop+i*l.lineHeight)
+		drawer.DrawString(line)
+	}
+
+	rule := image.Rect(l.padding, l.ruleTop, l.padding+l.ruleWidth, l.ruleTop+l.ruleHeight)
+	draw.Draw(img, rule, image.NewUniform(card.Theme.Accent), image.Point{}, draw.Src)
+
+	authorFace, err := newFace(boldFont, l.authorSize)
+	if err != nil {
+		return err
+	}
+	defer authorFace.Close()
+	drawer.Face = authorFace
+	drawer.Src = image.NewUniform(card.Theme.Accent)
+	drawer.Dot = fixed.P(l.padding, l.authorTop)
+	drawer.DrawString(l.author)
+
+	return png.Encode(w, img)
+}
+
+// SVG renders a card as an SVG document. Viewers without the Go fonts fall
+// back to a similar sans-serif font.
+func SVG(w io.Writer, card Card) error {
+	l, err := layOut(card)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		card.Size.Width, card.Size.Height, card.Size.Width, card.Size.Height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, hex(card.Theme.Background))
+	fmt.Fprintf(&b, `<text font-family="Go, 'Helvetica Neue', Arial, sans-serif" font-size="%.1f" fill="%s">`, l.textSize, hex(card.Theme.Text))
+	for i, line := range l.lines {
+		fmt.Fprintf(&b, `<tspan x="%d" y="%d">%s</tspan>`, l.padding, l.textTop+i*l.lineHeight, escape(line))
+	}
+	b.WriteString(`</text>`)
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, l.padding, l.ruleTop, l.ruleWidth, l.ruleHeight, hex(card.Theme.Accent))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="Go, 'Helvetica Neue', Arial, sans-serif" font-weight="bold" font-size="%.1f" fill="%s">%s</text>`,
+		l.padding, l.authorTop, l.authorSize, hex(card.Theme.Accent), escape(l.author))
+	b.WriteString(`</svg>`)
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+var svgEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&#39;")
+
+func escape(s string) string {
+	return svgEscaper.Replace(s)
+}
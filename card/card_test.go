@@ -0,0 +1,105 @@
+package card
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCard(text string) Card {
+	return Card{Text: text, Author: "Socrates", Theme: Themes[DefaultTheme], Size: Sizes[DefaultSize]}
+}
+
+func TestPNG(t *testing.T) {
+	for name, size := range Sizes {
+		c := testCard("The only true wisdom is in knowing you know nothing.")
+		c.Size = size
+
+		var buf bytes.Buffer
+		require.NoError(t, PNG(&buf, c), name)
+		img, err := png.Decode(&buf)
+		require.NoError(t, err, name)
+		assert.Equal(t, size.Width, img.Bounds().Dx(), name)
+		assert.Equal(t, size.Height, img.Bounds().Dy(), name)
+
+		// Something besides the background was drawn
+		background := Themes[DefaultTheme].Background
+		drawn := false
+		for y := 0; y < size.Height && !drawn; y += 4 {
+			for x := 0; x < size.Width; x += 4 {
+				r, g, b, _ := img.At(x, y).RGBA()
+				if uint8(r>>8) != background.R || uint8(g>>8) != background.G || uint8(b>>8) != background.B {
+					drawn = true
+					break
+				}
+			}
+		}
+		assert.True(t, drawn, name)
+	}
+}
+
+func TestLayOut(t *testing.T) {
+	short, err := layOut(testCard("Know thyself."))
+	require.NoError(t, err)
+	long, err := layOut(testCard(strings.Repeat("All that we are is the result of what we have thought. ", 6)))
+	require.NoError(t, err)
+
+	// Long quotes wrap onto more lines at a smaller size
+	assert.Len(t, short.lines, 1)
+	assert.Greater(t, len(long.lines), 3)
+	assert.Less(t, long.textSize, short.textSize)
+	assert.Equal(t, "— Socrates", short.author)
+
+	// Text too long for the smallest size is cut short
+	huge, err := layOut(testCard(strings.Repeat("word ", 2000)))
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(huge.lines[len(huge.lines)-1], "…"))
+	assert.LessOrEqual(t, huge.textTop+len(huge.lines)*huge.lineHeight, Sizes[DefaultSize].Height)
+
+	// Words wider than the card are split
+	unbroken, err := layOut(testCard(strings.Repeat("x", 500)))
+	require.NoError(t, err)
+	assert.Greater(t, len(unbroken.lines), 1)
+}
+
+func TestSVG(t *testing.T) {
+	c := testCard(`Fish & <chips> "quoted"` + "\x00")
+	c.Theme = Themes["dark"]
+
+	var buf bytes.Buffer
+	require.NoError(t, SVG(&buf, c))
+	svg := buf.String()
+
+	// The document is well formed and the text escaped
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+	assert.Contains(t, svg, "Fish &amp; &lt;chips&gt; &quot;quoted&quot;")
+	assert.Contains(t, svg, `fill="#161b22"`)
+	assert.Contains(t, svg, `width="1200" height="630"`)
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(2)
+	cache.Add("a", []byte("1"))
+	cache.Add("b", []byte("2"))
+	cache.Get("a")
+	cache.Add("c", []byte("3"))
+
+	_, ok := cache.Get("b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	data, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), data)
+	assert.Equal(t, 2, cache.Len())
+}
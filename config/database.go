@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"Qoute-backend/models"
 
@@ -30,16 +32,114 @@ func InitDB() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Quotes from before scheduled publishing count as published
+	backfillPublishedAt := !DB.Migrator().HasColumn(&models.Quote{}, "published_at")
+	// Author keys are normalized in Go, so they are filled in for older quotes
+	backfillAuthorKeys := !DB.Migrator().HasColumn(&models.Quote{}, "author_key")
+
+	// Follows were unique by author, compared with SQL LOWER. They are keyed
+	// by AuthorKey before the unique index on it is created.
+	if DB.Migrator().HasTable(&models.AuthorFollow{}) && !DB.Migrator().HasColumn(&models.AuthorFollow{}, "author_key") {
+		if err := migrateAuthorFollowKeys(); err != nil {
+			log.Fatal("Failed to backfill follow author keys:", err)
+		}
+	}
+
 	// Auto Migrate the schema
-	err = DB.AutoMigrate(&models.Quote{}, &models.User{}, &models.Vote{})
+	err = DB.AutoMigrate(&models.Quote{}, &models.User{}, &models.Vote{}, &models.Battle{}, &models.DailyQuote{}, &models.Report{}, &models.Collection{}, &models.CollectionItem{}, &models.Comment{}, &models.AuthorFollow{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// Backfill voter hashes for votes recorded before they were stored
+	var legacyVotes []models.Vote
+	if err := DB.Where("voter_hash IS NULL AND user_id IS NOT NULL").Find(&legacyVotes).Error; err != nil {
+		log.Fatal("Failed to load legacy votes:", err)
+	}
+	for _, vote := range legacyVotes {
+		if err := DB.Model(&vote).Update("voter_hash", VoterHash(*vote.UserID)).Error; err != nil {
+			log.Fatal("Failed to backfill voter hash:", err)
+		}
+	}
+
+	if backfillPublishedAt {
+		if err := DB.Model(&models.Quote{}).Unscoped().Where("published_at IS NULL AND status = ?", models.QuoteStatusApproved).UpdateColumn("published_at", gorm.Expr("created_at")).Error; err != nil {
+			log.Fatal("Failed to backfill publication times:", err)
+		}
+	}
+
+	if backfillAuthorKeys {
+		var quotes []models.Quote
+		if err := DB.Unscoped().Select("id", "author").Find(&quotes).Error; err != nil {
+			log.Fatal("Failed to load quote authors:", err)
+		}
+		for _, quote := range quotes {
+			if err := DB.Model(&quote).Unscoped().UpdateColumn("author_key", models.AuthorKey(quote.Author)).Error; err != nil {
+				log.Fatal("Failed to backfill author keys:", err)
+			}
+		}
+	}
+
+	// Promote configured admins
+	if admins := AdminUsernames(); len(admins) > 0 {
+		if err := DB.Model(&models.User{}).Where("username IN ?", admins).Update("role", models.RoleAdmin).Error; err != nil {
+			log.Fatal("Failed to promote admins:", err)
+		}
+	}
+
 	// Enable foreign key constraints for SQLite
 	db, err := DB.DB()
 	if err != nil {
 		log.Fatal("Failed to get database instance:", err)
 	}
 	db.SetMaxOpenConns(1) // SQLite only allows one write at a time
-}
\ No newline at end of file
+}
+
+// migrateAuthorFollowKeys adds and fills the author key of follows, and
+// drops follows that only differed from an earlier one by case
+func migrateAuthorFollowKeys() error {
+	migrator := DB.Migrator()
+	if migrator.HasIndex(&models.AuthorFollow{}, "idx_author_follows_user_author") {
+		if err := migrator.DropIndex(&models.AuthorFollow{}, "idx_author_follows_user_author"); err != nil {
+			return err
+		}
+	}
+	if err := migrator.AddColumn(&models.AuthorFollow{}, "AuthorKey"); err != nil {
+		return err
+	}
+
+	var follows []models.AuthorFollow
+	if err := DB.Order("id asc").Find(&follows).Error; err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, follow := range follows {
+		key := models.AuthorKey(follow.Author)
+		userKey := strconv.FormatUint(uint64(follow.UserID), 10) + ":" + key
+		if seen[userKey] {
+			if err := DB.Delete(&follow).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		seen[userKey] = true
+		if err := DB.Model(&follow).UpdateColumn("author_key", key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdminUsernames returns the users listed in ADMIN_USERNAMES, a comma
+// separated list. Existing users with these names are given the admin role
+// at startup; users registering later are not, so a listed name cannot be
+// claimed by someone else.
+func AdminUsernames() []string {
+	var usernames []string
+	for _, name := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			usernames = append(usernames, name)
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// intEnv reads a non-negative integer from an environment variable, falling
+// back to def when it is unset or invalid
+func intEnv(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value < 0 {
+		return def
+	}
+	return value
+}
+
+// AutoApproveThreshold is the number of approved quotes after which a user's
+// new quotes skip the moderation queue, from AUTO_APPROVE_THRESHOLD.
+// Zero, the default, disables auto-approval for regular users.
+func AutoApproveThreshold() int {
+	return intEnv("AUTO_APPROVE_THRESHOLD", 0)
+}
+
+// ReportHideThreshold is the number of open reports after which a quote is
+// hidden until a moderator looks at it, from REPORT_HIDE_THRESHOLD
+// (default 3). Zero disables automatic hiding.
+func ReportHideThreshold() int {
+	return intEnv("REPORT_HIDE_THRESHOLD", 3)
+}
+
+// ReportRateLimit is the number of reports a user can file per hour, from
+// REPORT_RATE_LIMIT (default 10)
+func ReportRateLimit() int {
+	return intEnv("REPORT_RATE_LIMIT", 10)
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// Vote privacy modes
+const (
+	// VotePrivacyPublic exposes who voted for what
+	VotePrivacyPublic = "public"
+	// VotePrivacyCounts stores voters but only ever reveals vote counts
+	VotePrivacyCounts = "counts"
+	// VotePrivacyAnonymous stores only a salted voter hash, never the user ID
+	VotePrivacyAnonymous = "anonymous"
+)
+
+// VotePrivacy returns the deployment's vote privacy mode from VOTE_PRIVACY.
+// Unknown or empty values fall back to VotePrivacyPublic.
+func VotePrivacy() string {
+	switch mode := os.Getenv("VOTE_PRIVACY"); mode {
+	case VotePrivacyCounts, VotePrivacyAnonymous:
+		return mode
+	default:
+		return VotePrivacyPublic
+	}
+}
+
+// VoterHash returns the salted hash identifying a user's votes. The salt is
+// read from VOTE_HASH_SALT and defaults to JWT_SECRET. CheckSecrets makes
+// sure it is set.
+func VoterHash(userID uint) string {
+	mac := hmac.New(sha256.New, []byte(voterHashSalt()))
+	fmt.Fprintf(mac, "voter:%d", userID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,14 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// PublicURL is the address the API is reached at, like
+// "https://quotes.example.com", used for links in feeds and share pages,
+// from PUBLIC_URL. When it is empty, links use the host of each request
+// and responses holding them are kept out of shared caches.
+func PublicURL() string {
+	return strings.TrimRight(os.Getenv("PUBLIC_URL"), "/")
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// ReceiptKey is a named secret used to sign vote receipts
+type ReceiptKey struct {
+	ID     string
+	Secret []byte
+}
+
+// ReceiptKeys returns the configured vote receipt signing keys.
+//
+// Keys are read from RECEIPT_KEYS as a comma separated list of "id:secret"
+// pairs. The first key signs new receipts; the remaining keys are only used
+// for verification so receipts issued before a key rotation stay valid.
+// When RECEIPT_KEYS is not set, JWT_SECRET is used under the id "default".
+// CheckSecrets makes sure the signing key is not empty.
+func ReceiptKeys() []ReceiptKey {
+	var keys []ReceiptKey
+	for _, pair := range strings.Split(os.Getenv("RECEIPT_KEYS"), ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" || secret == "" {
+			continue
+		}
+		keys = append(keys, ReceiptKey{ID: id, Secret: []byte(secret)})
+	}
+
+	if len(keys) == 0 {
+		keys = append(keys, ReceiptKey{ID: "default", Secret: []byte(os.Getenv("JWT_SECRET"))})
+	}
+	return keys
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// CheckSecrets reports a missing signing key. Vote receipts and voter
+// hashes are HMACs, so an empty key would let anyone forge receipts or
+// recover voters by hashing user IDs.
+func CheckSecrets() error {
+	if len(ReceiptKeys()[0].Secret) == 0 {
+		return errors.New("vote receipts need RECEIPT_KEYS or JWT_SECRET to be set")
+	}
+	if voterHashSalt() == "" {
+		return errors.New("voter hashes need VOTE_HASH_SALT or JWT_SECRET to be set")
+	}
+	return nil
+}
+
+// voterHashSalt returns the salt of voter hashes, from VOTE_HASH_SALT or
+// else JWT_SECRET
+func voterHashSalt() string {
+	if salt := os.Getenv("VOTE_HASH_SALT"); salt != "" {
+		return salt
+	}
+	return os.Getenv("JWT_SECRET")
+}
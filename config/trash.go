@@ -0,0 +1,40 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// durationEnv reads a Go duration such as "720h" from an environment
+// variable, falling back to def when it is unset or invalid
+func durationEnv(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+// TrashRetention is how long deleted quotes stay in the trash before they
+// are purged, from TRASH_RETENTION (default 30 days)
+func TrashRetention() time.Duration {
+	return durationEnv("TRASH_RETENTION", 30*24*time.Hour)
+}
+
+// TrashPurgeInterval is how often the trash is purged, from
+// TRASH_PURGE_INTERVAL (default 1 hour)
+func TrashPurgeInterval() time.Duration {
+	return durationEnv("TRASH_PURGE_INTERVAL", time.Hour)
+}
+
+// PublishInterval is how often scheduled quotes are checked for publication,
+// from PUBLISH_INTERVAL (default 30 seconds)
+func PublishInterval() time.Duration {
+	return durationEnv("PUBLISH_INTERVAL", 30*time.Second)
+}
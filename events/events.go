@@ -0,0 +1,88 @@
+// Package events is a small in-process event bus. Background jobs publish
+// events and other parts of the server subscribe to react to them.
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	QuotePublished = "quote.published" // A scheduled quote went live
+)
+
+// Event is something that happened to a quote
+type Event struct {
+	Type    string
+	QuoteID uint
+	At      time.Time
+}
+
+// Handler reacts to an event. Handlers run synchronously in the publishing
+// goroutine, so slow work should be handed off.
+type Handler func(Event)
+
+// Bus delivers published events to subscribers. The zero value is ready to
+// use.
+type Bus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]subscription
+}
+
+type subscription struct {
+	eventType string
+	handler   Handler
+}
+
+// NewBus returns an empty bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers handler for events of eventType, or for every event
+// when eventType is empty. The returned function unsubscribes.
+func (b *Bus) Subscribe(eventType string, handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.handlers == nil {
+		b.handlers = map[int]subscription{}
+	}
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = subscription{eventType, handler}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish delivers an event to its subscribers. A panicking handler is
+// logged and does not stop delivery to the others.
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	var handlers []Handler
+	for _, s := range b.handlers {
+		if s.eventType == "" || s.eventType == event.Type {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		deliver(handler, event)
+	}
+}
+
+func deliver(handler Handler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Event handler for %s panicked: %v", event.Type, r)
+		}
+	}()
+	handler(event)
+}
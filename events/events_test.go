@@ -0,0 +1,24 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	var bus Bus
+	var published, all []uint
+
+	unsubscribe := bus.Subscribe(QuotePublished, func(e Event) { published = append(published, e.QuoteID) })
+	bus.Subscribe("", func(e Event) { all = append(all, e.QuoteID) })
+	bus.Subscribe(QuotePublished, func(Event) { panic("broken handler") })
+
+	bus.Publish(Event{Type: QuotePublished, QuoteID: 1})
+	bus.Publish(Event{Type: "other", QuoteID: 2})
+	unsubscribe()
+	bus.Publish(Event{Type: QuotePublished, QuoteID: 3})
+
+	assert.Equal(t, []uint{1}, published)
+	assert.Equal(t, []uint{1, 2, 3}, all)
+}
@@ -0,0 +1,140 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the type of a filterable field, deciding which operators and
+// values it accepts
+type Type int
+
+const (
+	String Type = iota
+	Number
+	Time
+	Bool
+)
+
+// Field maps a filter field name to trusted SQL
+type Field struct {
+	SQL  string // Column or expression, never built from user input
+	Type Type
+}
+
+// Compile turns an expression into a SQL condition and its arguments.
+// Fields not in fields are rejected.
+func Compile(node Node, fields map[string]Field) (string, []interface{}, error) {
+	var args []interface{}
+	sql, err := compile(node, fields, &args)
+	return sql, args, err
+}
+
+func compile(node Node, fields map[string]Field, args *[]interface{}) (string, error) {
+	switch n := node.(type) {
+	case Logical:
+		left, err := compile(n.Left, fields, args)
+		if err != nil {
+			return "", err
+		}
+		right, err := compile(n.Right, fields, args)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + strings.ToUpper(n.Op) + " " + right + ")", nil
+	case Not:
+		expr, err := compile(n.Expr, fields, args)
+		if err != nil {
+			return "", err
+		}
+		return "(NOT " + expr + ")", nil
+	case Comparison:
+		return compileComparison(n, fields, args)
+	default:
+		return "", fmt.Errorf("unknown node %T", node)
+	}
+}
+
+func compileComparison(n Comparison, fields map[string]Field, args *[]interface{}) (string, error) {
+	field, ok := fields[n.Field]
+	if !ok {
+		return "", &Error{n.Position, fmt.Sprintf("unknown field %q, use one of %s", n.Field, strings.Join(fieldNames(fields), ", "))}
+	}
+
+	op := n.Op
+	if op == ":" {
+		op = "="
+	}
+	if op == "!=" {
+		op = "<>"
+	}
+
+	var value interface{}
+	switch field.Type {
+	case String:
+		switch op {
+		case "~":
+			*args = append(*args, "%"+EscapeLike(n.Value)+"%")
+			return field.SQL + ` LIKE ? ESCAPE '\'`, nil
+		case "=", "<>":
+			value = n.Value
+		default:
+			return "", &Error{n.Position, fmt.Sprintf("%s only supports :, =, != and ~", n.Field)}
+		}
+	case Number:
+		number, err := strconv.ParseFloat(n.Value, 64)
+		if err != nil || n.Quoted {
+			return "", &Error{n.Position, fmt.Sprintf("%s must be compared with a number", n.Field)}
+		}
+		value = number
+	case Time:
+		parsed, err := ParseTime(n.Value)
+		if err != nil {
+			return "", &Error{n.Position, fmt.Sprintf("%s must be compared with a date like 2024-01-31 or an RFC 3339 time", n.Field)}
+		}
+		value = parsed
+	case Bool:
+		parsed, err := strconv.ParseBool(n.Value)
+		if err != nil || (op != "=" && op != "<>") {
+			return "", &Error{n.Position, fmt.Sprintf("%s must be compared with true or false using : or !=", n.Field)}
+		}
+		value = parsed
+	}
+	if op == "~" {
+		return "", &Error{n.Position, fmt.Sprintf("~ only applies to text fields, not %s", n.Field)}
+	}
+
+	*args = append(*args, value)
+	return field.SQL + " " + op + " ?", nil
+}
+
+// ParseTime parses a date (2024-01-31) or an RFC 3339 time. Dates are
+// midnight in the local time zone.
+func ParseTime(value string) (time.Time, error) {
+	if parsed, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Stored times are local, so compare in local time
+	return parsed.In(time.Local), nil
+}
+
+// EscapeLike escapes the LIKE wildcards in a value, for use with ESCAPE '\'
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func fieldNames(fields map[string]Field) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
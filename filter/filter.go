@@ -0,0 +1,295 @@
+// Package filter parses compact filter expressions such as
+//
+//	votes>5 and author:"Socrates" or not (language:fr)
+//
+// into an AST and compiles them to parameterized SQL over a whitelist of
+// fields. Values never end up in the SQL text, only in its arguments.
+//
+// Comparisons are field, operator and value. The operators are ":" and "="
+// for equality, "!=", "<", "<=", ">", ">=" and "~" for "contains". Values
+// are numbers, double quoted strings or bare words. "and" binds tighter than
+// "or", "not" negates and parentheses group.
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits protecting the parser and database from huge expressions
+const (
+	MaxLength = 1000
+	MaxDepth  = 20
+)
+
+// Error describes a malformed expression
+type Error struct {
+	Position int    // Byte offset in the expression
+	Message  string // What went wrong
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Position, e.Message)
+}
+
+// Node is a node of a parsed expression
+type Node interface {
+	node()
+}
+
+// Logical combines two expressions with "and" or "or"
+type Logical struct {
+	Op          string
+	Left, Right Node
+}
+
+// Not negates an expression
+type Not struct {
+	Expr Node
+}
+
+// Comparison compares a field with a literal value
+type Comparison struct {
+	Field    string
+	Op       string
+	Value    string
+	Quoted   bool // Whether the value was a quoted string
+	Position int
+}
+
+func (Logical) node()    {}
+func (Not) node()        {}
+func (Comparison) node() {}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	position int
+}
+
+var operators = []string{">=", "<=", "!=", ":", "=", "<", ">", "~"}
+
+// isWordRune reports whether r can be part of a bare word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		r := rune(input[i])
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenOpen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenClose, ")", i})
+			i++
+		case r == '"':
+			start := i
+			var text strings.Builder
+			i++
+			for {
+				if i >= len(input) {
+					return nil, &Error{start, "unterminated string"}
+				}
+				if input[i] == '\\' && i+1 < len(input) {
+					text.WriteByte(input[i+1])
+					i += 2
+					continue
+				}
+				if input[i] == '"' {
+					i++
+					break
+				}
+				text.WriteByte(input[i])
+				i++
+			}
+			tokens = append(tokens, token{tokenString, text.String(), start})
+		default:
+			matched := false
+			for _, operator := range operators {
+				if strings.HasPrefix(input[i:], operator) {
+					tokens = append(tokens, token{tokenOperator, operator, i})
+					i += len(operator)
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+			start := i
+			for i < len(input) {
+				r, size := utf8.DecodeRuneInString(input[i:])
+				if !isWordRune(r) {
+					break
+				}
+				i += size
+			}
+			if i == start {
+				return nil, &Error{start, fmt.Sprintf("unexpected character %q", input[start:start+1])}
+			}
+			tokens = append(tokens, token{tokenWord, input[start:i], start})
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(input)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, word)
+}
+
+// Parse parses an expression into its AST
+func Parse(input string) (Node, error) {
+	if len(input) > MaxLength {
+		return nil, &Error{MaxLength, fmt.Sprintf("expression is longer than %d characters", MaxLength)}
+	}
+	if strings.TrimSpace(input) == "" {
+		return nil, &Error{0, "expression is empty"}
+	}
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &Error{t.position, fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return node, nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{"or", left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{"and", left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > MaxDepth {
+		return nil, &Error{p.peek().position, fmt.Sprintf("expression is nested deeper than %d levels", MaxDepth)}
+	}
+
+	if p.keyword("not") {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{expr}, nil
+	}
+
+	if p.peek().kind == tokenOpen {
+		open := p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenClose {
+			return nil, &Error{open.position, "unclosed parenthesis"}
+		}
+		p.next()
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	field := p.next()
+	if field.kind != tokenWord {
+		return nil, &Error{field.position, describe(field, "a field name")}
+	}
+	operator := p.next()
+	if operator.kind != tokenOperator {
+		return nil, &Error{operator.position, describe(operator, "an operator after "+strconv.Quote(field.text))}
+	}
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, &Error{value.position, describe(value, "a value after "+strconv.Quote(operator.text))}
+	}
+	return Comparison{
+		Field:    strings.ToLower(field.text),
+		Op:       operator.text,
+		Value:    value.text,
+		Quoted:   value.kind == tokenString,
+		Position: field.position,
+	}, nil
+}
+
+// describe explains what was expected instead of a token
+func describe(t token, expected string) string {
+	if t.kind == tokenEOF {
+		return "expected " + expected + " but the expression ended"
+	}
+	return fmt.Sprintf("expected %s but found %q", expected, t.text)
+}
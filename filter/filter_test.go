@@ -0,0 +1,65 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFields = map[string]Field{
+	"author": {SQL: "quotes.author", Type: String},
+	"votes":  {SQL: "vote_count", Type: Number},
+	"date":   {SQL: "quotes.created_at", Type: Time},
+	"ok":     {SQL: "quotes.ok", Type: Bool},
+}
+
+func TestParseAndCompile(t *testing.T) {
+	node, err := Parse(`votes>5 and author:"Socrates" or not (author ~ "50%_off" and ok:true)`)
+	assert.NoError(t, err)
+
+	sql, args, err := Compile(node, testFields)
+	assert.NoError(t, err)
+	assert.Equal(t, `((vote_count > ? AND quotes.author = ?) OR (NOT (quotes.author LIKE ? ESCAPE '\' AND quotes.ok = ?)))`, sql)
+	assert.Equal(t, []interface{}{5.0, "Socrates", `%50\%\_off%`, true}, args)
+}
+
+func TestParseValuesStayOutOfSQL(t *testing.T) {
+	node, err := Parse(`author:"x' OR 1=1 --"`)
+	assert.NoError(t, err)
+	sql, args, err := Compile(node, testFields)
+	assert.NoError(t, err)
+	assert.Equal(t, "quotes.author = ?", sql)
+	assert.Equal(t, []interface{}{"x' OR 1=1 --"}, args)
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]int{
+		``:                     0,
+		`votes >`:              7,
+		`votes 5`:              6,
+		`(votes > 5`:           0,
+		`votes > 5 author:"a"`: 10,
+		`author:"open`:         7,
+		`votes > 5 $`:          10,
+		strings.Repeat("(", MaxDepth+1) + "votes>1" + strings.Repeat(")", MaxDepth+1): MaxDepth,
+	}
+	for input, position := range cases {
+		_, err := Parse(input)
+		var filterErr *Error
+		if assert.True(t, errors.As(err, &filterErr), input) {
+			assert.Equal(t, position, filterErr.Position, input)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, input := range []string{`rating>5`, `votes:"many"`, `votes~1`, `date>yesterday`, `ok>true`, `author>b`} {
+		node, err := Parse(input)
+		assert.NoError(t, err, input)
+		_, _, err = Compile(node, testFields)
+		var filterErr *Error
+		assert.True(t, errors.As(err, &filterErr), input)
+	}
+}
@@ -0,0 +1,170 @@
+package formats
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumns sets the field of a record named by each CSV column. Dots in
+// column names may be written as underscores, as in source_year.
+var csvColumns = map[string]func(r *record, value string) error{
+	"content":  func(r *record, value string) error { r.Content = value; return nil },
+	"author":   func(r *record, value string) error { r.Author = value; return nil },
+	"language": func(r *record, value string) error { r.Language = value; return nil },
+	"translation_of": func(r *record, value string) error {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			return errors.New("must be a quote ID")
+		}
+		translationOf := uint(id)
+		r.TranslationOf = &translationOf
+		return nil
+	},
+	"publish_at": func(r *record, value string) error {
+		publishAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return errors.New("must be an RFC 3339 time")
+		}
+		r.PublishAt = &publishAt
+		return nil
+	},
+	"source.work_title": func(r *record, value string) error { r.Source.WorkTitle = value; return nil },
+	"source.year": func(r *record, value string) error {
+		year, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be a whole number")
+		}
+		r.Source.Year = &year
+		return nil
+	},
+	"source.page":    func(r *record, value string) error { r.Source.Page = value; return nil },
+	"source.url":     func(r *record, value string) error { r.Source.URL = value; return nil },
+	"source.license": func(r *record, value string) error { r.Source.License = value; return nil },
+}
+
+// QuoteColumns are the CSV columns of exported quotes. Imports ignore the
+// ones they cannot set, so exported files can be imported again.
+var QuoteColumns = []string{
+	"id", "content", "author", "language", "translation_of",
+	"source_work_title", "source_year", "source_page", "source_url", "source_license", "source_verification",
+	"status", "hidden", "user_id", "rating", "battles", "vote_count", "comment_count",
+	"publish_at", "published_at", "created_at", "updated_at",
+}
+
+// csvColumnName normalizes a column name from a CSV header
+func csvColumnName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if strings.HasPrefix(name, "source_") {
+		name = "source." + strings.TrimPrefix(name, "source_")
+	}
+	return name
+}
+
+// csvReader reads a CSV file whose first record names the columns. Rows are
+// numbered from 1 after the header.
+type csvReader struct {
+	csv     *csv.Reader
+	columns []string
+	rows    int
+	err     error
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Checked per row, so a short row does not end the file
+	reader.ReuseRecord = true
+	return &csvReader{csv: reader}
+}
+
+func (r *csvReader) readHeader() error {
+	header, err := r.csv.Read()
+	if err == io.EOF {
+		return &SyntaxError{Line: 1, Msg: "missing header"}
+	}
+	if err != nil {
+		return csvSyntaxError(err)
+	}
+
+	exported := map[string]bool{}
+	for _, column := range QuoteColumns {
+		exported[csvColumnName(column)] = true
+	}
+
+	seen := map[string]bool{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Byte order mark
+		}
+		name = csvColumnName(name)
+		if _, ok := csvColumns[name]; !ok && !exported[name] {
+			known := make([]string, 0, len(csvColumns))
+			for column := range csvColumns {
+				known = append(known, column)
+			}
+			sort.Strings(known)
+			return &SyntaxError{Line: 1, Msg: fmt.Sprintf("unknown column %q, expected some of %s", header[i], strings.Join(known, ", "))}
+		}
+		if seen[name] {
+			return &SyntaxError{Line: 1, Msg: fmt.Sprintf("column %q appears twice", header[i])}
+		}
+		seen[name] = true
+		r.columns = append(r.columns, name)
+	}
+	if !seen["content"] || !seen["author"] {
+		return &SyntaxError{Line: 1, Msg: "the content and author columns are required"}
+	}
+	return nil
+}
+
+func (r *csvReader) Read() (Row, error) {
+	if r.err != nil {
+		return Row{}, r.err
+	}
+	if r.columns == nil {
+		if r.err = r.readHeader(); r.err != nil {
+			return Row{}, r.err
+		}
+	}
+
+	fields, err := r.csv.Read()
+	if err != nil {
+		if err != io.EOF {
+			err = csvSyntaxError(err)
+		}
+		r.err = err
+		return Row{}, err
+	}
+	r.rows++
+
+	row := Row{Line: r.rows, Problems: map[string]string{}}
+	if len(fields) != len(r.columns) {
+		row.Problems["row"] = fmt.Sprintf("has %d fields, expected %d", len(fields), len(r.columns))
+		return row, nil
+	}
+	var rec record
+	for i, value := range fields {
+		set, ok := csvColumns[r.columns[i]]
+		if value == "" || !ok {
+			continue
+		}
+		if err := set(&rec, value); err != nil {
+			row.Problems[r.columns[i]] = err.Error()
+		}
+	}
+	row.Quote = rec.quote()
+	return row, nil
+}
+
+func csvSyntaxError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &SyntaxError{Line: parseErr.Line, Msg: parseErr.Err.Error()}
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+// Package formats reads and writes the file formats of quote imports and
+// exports. Readers only parse: validating and storing the quotes is left to
+// the caller.
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"Qoute-backend/models"
+)
+
+// Format names
+const (
+	CSV       = "csv"
+	JSON      = "json"      // An array of quote objects
+	NDJSON    = "ndjson"    // One quote object per line
+	Fortune   = "fortune"   // The text files of the Unix fortune program
+	Kindle    = "kindle"    // The "My Clippings.txt" file of Kindle e-readers
+	Goodreads = "goodreads" // Quotes exported from Goodreads as CSV
+)
+
+// Names lists the formats that can be read
+var Names = []string{CSV, JSON, NDJSON, Fortune, Kindle, Goodreads}
+
+// ErrUnknownFormat is returned for formats that are not supported
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Row is a quote read from a file. Problems with single fields, such as a
+// year that is not a number, are reported by field name and leave the rest
+// of the row usable. Problems with the whole row use the key "row". Entries
+// that are not quotes, like bookmarks, are read as rows that say why they
+// were skipped.
+type Row struct {
+	Line     int // Row number, counted as described by each format
+	Quote    models.Quote
+	Problems map[string]string
+	Skipped  string
+}
+
+// Reader reads rows one at a time. Read returns io.EOF after the last row.
+type Reader interface {
+	Read() (Row, error)
+}
+
+// SyntaxError is a problem that stops a file from being read any further
+type SyntaxError struct {
+	Line int // Line of the file, 0 when not known
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Supported reports whether a format can be read
+func Supported(format string) bool {
+	return slices.Contains(Names, format)
+}
+
+// NewReader returns a reader for the named format
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case CSV:
+		return newCSVReader(r), nil
+	case JSON:
+		return newJSONReader(r), nil
+	case NDJSON:
+		return newNDJSONReader(r), nil
+	case Fortune:
+		return newFortuneReader(r), nil
+	case Kindle:
+		return newKindleReader(r), nil
+	case Goodreads:
+		return newGoodreadsReader(r), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Detect returns the format of a file from its file name or media type. It
+// returns an empty string when neither is known. Fortune files have
+// neither, so they are never detected.
+func Detect(contentType, filename string) string {
+	name := strings.ToLower(path.Base(filename))
+	switch {
+	case name == "my clippings.txt":
+		return Kindle
+	case strings.Contains(name, "goodreads") && path.Ext(name) == ".csv":
+		return Goodreads
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return CSV
+	case "application/json":
+		return JSON
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return NDJSON
+	}
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return CSV
+	case ".json":
+		return JSON
+	case ".ndjson", ".jsonl":
+		return NDJSON
+	}
+	return ""
+}
+
+// record holds the quote fields files can set, named as in the API
+type record struct {
+	Content       string          `json:"content"`
+	Author        string          `json:"author"`
+	Source        models.Citation `json:"source"`
+	Language      string          `json:"language"`
+	TranslationOf *uint           `json:"translation_of"`
+	PublishAt     *time.Time      `json:"publish_at"`
+}
+
+func (r record) quote() models.Quote {
+	return models.Quote{
+		Content:       r.Content,
+		Author:        r.Author,
+		Source:        r.Source,
+		Language:      r.Language,
+		TranslationOf: r.TranslationOf,
+		PublishAt:     r.PublishAt,
+	}
+}
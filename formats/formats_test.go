@@ -0,0 +1,179 @@
+package formats
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAll reads every row, stopping at the first error other than io.EOF
+func readAll(t *testing.T, format, input string) ([]Row, error) {
+	t.Helper()
+	reader, err := NewReader(format, strings.NewReader(input))
+	require.NoError(t, err)
+	var rows []Row
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	input := "\ufeffContent,author,source_year,source.work_title,publish_at\n" +
+		"\"Stay hungry, stay foolish.\",Steve Jobs,2005,Stanford address,\n" +
+		"Be the change.,Gandhi,long ago,,tomorrow\n" +
+		"Too short\n"
+	rows, err := readAll(t, CSV, input)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Empty(t, rows[0].Problems)
+	assert.Equal(t, "Stay hungry, stay foolish.", rows[0].Quote.Content)
+	assert.Equal(t, "Steve Jobs", rows[0].Quote.Author)
+	assert.Equal(t, 2005, *rows[0].Quote.Source.Year)
+	assert.Equal(t, "Stanford address", rows[0].Quote.Source.WorkTitle)
+	assert.Nil(t, rows[0].Quote.PublishAt)
+
+	assert.Equal(t, map[string]string{"source.year": "must be a whole number", "publish_at": "must be an RFC 3339 time"}, rows[1].Problems)
+	assert.Equal(t, "Be the change.", rows[1].Quote.Content)
+	assert.Equal(t, map[string]string{"row": "has 1 fields, expected 5"}, rows[2].Problems)
+}
+
+func TestCSVHeader(t *testing.T) {
+	var syntaxErr *SyntaxError
+	_, err := readAll(t, CSV, "content,writer\nHello,World\n")
+	require.True(t, errors.As(err, &syntaxErr))
+	assert.Equal(t, 1, syntaxErr.Line)
+	assert.Contains(t, syntaxErr.Msg, `unknown column "writer"`)
+
+	_, err = readAll(t, CSV, "content,language\nHello,en\n")
+	assert.ErrorContains(t, err, "content and author columns are required")
+	_, err = readAll(t, CSV, "")
+	assert.ErrorContains(t, err, "missing header")
+
+	_, err = readAll(t, CSV, "content,author\n\"Unclosed,Someone\n")
+	require.True(t, errors.As(err, &syntaxErr))
+	assert.Equal(t, 2, syntaxErr.Line)
+}
+
+func TestJSON(t *testing.T) {
+	input := `[
+		{"content": "Stay hungry.", "author": "Steve Jobs", "source": {"year": 2005}, "language": "en"},
+		{"content": "Be the change.", "author": "Gandhi", "source": {"year": "long ago"}},
+		"not a quote",
+		{"content": "Later.", "author": "Someone", "publish_at": "tomorrow"}
+	]`
+	rows, err := readAll(t, JSON, input)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Empty(t, rows[0].Problems)
+	assert.Equal(t, "Stay hungry.", rows[0].Quote.Content)
+	assert.Equal(t, 2005, *rows[0].Quote.Source.Year)
+	assert.Equal(t, "en", rows[0].Quote.Language)
+	assert.Equal(t, map[string]string{"source.year": "must be a int"}, rows[1].Problems)
+	assert.Equal(t, "Be the change.", rows[1].Quote.Content, "other fields are still read")
+	assert.Equal(t, map[string]string{"row": "must be a quote object"}, rows[2].Problems)
+	assert.Equal(t, map[string]string{"publish_at": "must be an RFC 3339 time"}, rows[3].Problems)
+	assert.Equal(t, 4, rows[3].Line)
+
+	_, err = readAll(t, JSON, `{"content": "Not in an array"}`)
+	assert.ErrorContains(t, err, "expected an array of quotes")
+	rows, err = readAll(t, JSON, `[{"content": "a", "author": "b"}, {"content": `)
+	assert.Len(t, rows, 1)
+	assert.ErrorContains(t, err, "unexpected end of file")
+	rows, err = readAll(t, JSON, `[]`)
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestNDJSON(t *testing.T) {
+	input := "{\"content\": \"Stay hungry.\", \"author\": \"Steve Jobs\"}\n\n" +
+		"{\"content\": \"Broken\n" +
+		"{\"content\": \"Be the change.\", \"author\": \"Gandhi\"}"
+	rows, err := readAll(t, NDJSON, input)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, "Steve Jobs", rows[0].Quote.Author)
+	assert.Equal(t, 3, rows[1].Line, "blank lines are counted")
+	assert.Contains(t, rows[1].Problems["row"], "invalid JSON")
+	assert.Equal(t, 4, rows[2].Line)
+	assert.Equal(t, "Be the change.", rows[2].Quote.Content)
+}
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, CSV, Detect("text/csv; charset=utf-8", ""))
+	assert.Equal(t, NDJSON, Detect("application/x-ndjson", "quotes.json"))
+	assert.Equal(t, JSON, Detect("application/octet-stream", "quotes.JSON"))
+	assert.Equal(t, NDJSON, Detect("", "quotes.jsonl"))
+	assert.Equal(t, "", Detect("text/plain", "quotes.txt"))
+	assert.Equal(t, Kindle, Detect("text/plain", "My Clippings.txt"))
+	assert.Equal(t, Goodreads, Detect("text/csv", "goodreads_quotes.csv"))
+
+	assert.True(t, Supported(NDJSON))
+	assert.False(t, Supported("xml"))
+	_, err := NewReader("xml", strings.NewReader(""))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+type testRecord struct {
+	Content string `json:"content"`
+	Author  string `json:"author"`
+}
+
+func (r testRecord) CSVFields() []string {
+	return []string{r.Content, r.Author}
+}
+
+func TestWriter(t *testing.T) {
+	records := []testRecord{{"Stay hungry, stay foolish.", "Steve Jobs"}, {"Be \"the\" change.", "Gandhi"}}
+	write := func(format string, records []testRecord) string {
+		var out strings.Builder
+		writer, err := NewWriter(format, &out, []string{"content", "author"})
+		require.NoError(t, err)
+		for _, record := range records {
+			require.NoError(t, writer.Write(record))
+		}
+		require.NoError(t, writer.Close())
+		return out.String()
+	}
+
+	assert.Equal(t, "content,author\n\"Stay hungry, stay foolish.\",Steve Jobs\n\"Be \"\"the\"\" change.\",Gandhi\n", write(CSV, records))
+	assert.Equal(t, "[\n{\"content\":\"Stay hungry, stay foolish.\",\"author\":\"Steve Jobs\"},\n{\"content\":\"Be \\\"the\\\" change.\",\"author\":\"Gandhi\"}\n]\n", write(JSON, records))
+	assert.Equal(t, "[]\n", write(JSON, nil))
+	assert.Equal(t, "{\"content\":\"Stay hungry, stay foolish.\",\"author\":\"Steve Jobs\"}\n{\"content\":\"Be \\\"the\\\" change.\",\"author\":\"Gandhi\"}\n", write(NDJSON, records))
+
+	// What is written can be read back
+	for _, format := range WriterNames {
+		rows, err := readAll(t, format, write(format, records))
+		require.NoError(t, err, format)
+		require.Len(t, rows, 2, format)
+		assert.Equal(t, "Be \"the\" change.", rows[1].Quote.Content, format)
+		assert.Equal(t, "Gandhi", rows[1].Quote.Author, format)
+	}
+
+	_, err := NewWriter("xml", &strings.Builder{}, nil)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestCSVExportedColumns(t *testing.T) {
+	// Columns of exported quotes that imports cannot set are ignored
+	rows, err := readAll(t, CSV, "id,content,author,vote_count,source_verification\n7,Stay hungry.,Steve Jobs,12,verified\n")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Empty(t, rows[0].Problems)
+	assert.Zero(t, rows[0].Quote.ID)
+	assert.Empty(t, rows[0].Quote.Source.Verification)
+	assert.Equal(t, "Stay hungry.", rows[0].Quote.Content)
+}
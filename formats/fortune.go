@@ -0,0 +1,183 @@
+package formats
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// fortuneDelimiter is the line between two fortunes
+const fortuneDelimiter = "%"
+
+// strfileVersion is the version of the strfile index written
+const strfileVersion = 2
+
+var (
+	// fortuneAttribution matches the line naming who said a fortune, like
+	// "		-- Mark Twain"
+	fortuneAttribution = regexp.MustCompile(`^\s*(?:--|—|–|―)\s*(\S.*?)\s*$`)
+	// fortuneWork splits a work title off an attribution, as in
+	// `Mark Twain, "Following the Equator"`
+	fortuneWork = regexp.MustCompile(`^(.+?),\s*["“](.+)["”]$`)
+)
+
+// fortuneReader reads a fortune file: quotes separated by lines holding only
+// "%", each ending with an attribution line. Rows are numbered by the line
+// they start on.
+type fortuneReader struct {
+	scanner *bufio.Scanner
+	line    int
+	done    bool
+}
+
+func newFortuneReader(r io.Reader) *fortuneReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	return &fortuneReader{scanner: scanner}
+}
+
+func (r *fortuneReader) Read() (Row, error) {
+	for !r.done {
+		start := r.line + 1
+		var lines []string
+		for {
+			if !r.scanner.Scan() {
+				if err := r.scanner.Err(); err != nil {
+					if errors.Is(err, bufio.ErrTooLong) {
+						return Row{}, &SyntaxError{Line: r.line + 1, Msg: "line too long"}
+					}
+					return Row{}, err
+				}
+				r.done = true
+				break
+			}
+			r.line++
+			line := strings.TrimSuffix(r.scanner.Text(), "\r")
+			if line == fortuneDelimiter {
+				break
+			}
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+
+		// Empty fortunes, like one after the last delimiter, are skipped
+		for len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		for len(lines) > 0 && lines[0] == "" {
+			lines, start = lines[1:], start+1
+		}
+		if len(lines) > 0 {
+			return parseFortune(start, lines), nil
+		}
+	}
+	return Row{}, io.EOF
+}
+
+// parseFortune makes a row of the lines of one fortune. Fortunes without an
+// attribution are read without an author.
+func parseFortune(line int, lines []string) Row {
+	var rec record
+	if match := fortuneAttribution.FindStringSubmatch(lines[len(lines)-1]); match != nil && len(lines) > 1 {
+		rec.Author = match[1]
+		if work := fortuneWork.FindStringSubmatch(rec.Author); work != nil {
+			rec.Author, rec.Source.WorkTitle = work[1], work[2]
+		}
+		lines = lines[:len(lines)-1]
+	}
+	rec.Content = strings.Join(lines, "\n")
+	return Row{Line: line, Quote: rec.quote(), Problems: map[string]string{}}
+}
+
+// FortuneWriter writes quotes as a fortune file, keeping the offsets the
+// strfile index needs. Only the index is held in memory, four bytes per
+// quote.
+type FortuneWriter struct {
+	w        io.Writer
+	offset   int64
+	offsets  []uint32 // Start of each fortune
+	longest  uint32
+	shortest uint32
+}
+
+// NewFortuneWriter returns a writer of fortunes to w
+func NewFortuneWriter(w io.Writer) *FortuneWriter {
+	return &FortuneWriter{w: w, offsets: []uint32{0}}
+}
+
+// Write writes one quote, attributed to its author and work if known
+func (w *FortuneWriter) Write(content, author, workTitle string) error {
+	var text strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == fortuneDelimiter {
+			line = " " + line // Would end the fortune
+		}
+		text.WriteString(line + "\n")
+	}
+	if author = strings.Join(strings.Fields(author), " "); author != "" {
+		if workTitle = strings.Join(strings.Fields(workTitle), " "); workTitle != "" {
+			author += `, "` + workTitle + `"`
+		}
+		text.WriteString("\t\t-- " + author + "\n")
+	}
+
+	length := int64(text.Len())
+	text.WriteString(fortuneDelimiter + "\n")
+	if w.offset+int64(text.Len()) > math.MaxUint32 {
+		return errors.New("fortune files are limited to 4 GB")
+	}
+	if _, err := io.WriteString(w.w, text.String()); err != nil {
+		return err
+	}
+
+	w.offset += int64(text.Len())
+	w.offsets = append(w.offsets, uint32(w.offset))
+	if uint32(length) > w.longest {
+		w.longest = uint32(length)
+	}
+	if w.shortest == 0 || uint32(length) < w.shortest {
+		w.shortest = uint32(length)
+	}
+	return nil
+}
+
+// Len returns the number of bytes written
+func (w *FortuneWriter) Len() int64 {
+	return w.offset
+}
+
+// strfileHeader is the start of a strfile index, as in fortune's strfile.h
+type strfileHeader struct {
+	Version  uint32
+	NumStr   uint32 // Number of fortunes
+	LongLen  uint32 // Length of the longest fortune
+	ShortLen uint32 // Length of the shortest fortune
+	Flags    uint32 // Neither random nor ordered
+	Delim    [4]byte
+}
+
+// WriteIndex writes the strfile index of the fortunes written so far: the
+// .dat file fortune reads to pick a fortune without reading the whole file.
+// It holds a header and the offset of each fortune, big-endian.
+func (w *FortuneWriter) WriteIndex(out io.Writer) error {
+	header := strfileHeader{
+		Version:  strfileVersion,
+		NumStr:   uint32(len(w.offsets) - 1),
+		LongLen:  w.longest,
+		ShortLen: w.shortest,
+		Delim:    [4]byte{fortuneDelimiter[0]},
+	}
+	if err := binary.Write(out, binary.BigEndian, header); err != nil {
+		return err
+	}
+	return binary.Write(out, binary.BigEndian, w.offsets)
+}
+
+// IndexLen returns the size of the index WriteIndex writes
+func (w *FortuneWriter) IndexLen() int64 {
+	return int64(binary.Size(strfileHeader{})) + 4*int64(len(w.offsets))
+}
@@ -0,0 +1,106 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFortuneReader(t *testing.T) {
+	input := "%\n" +
+		"The only true wisdom is in knowing you know nothing.\n" +
+		"\t\t-- Socrates\n" +
+		"%\n" +
+		"\n" +
+		"Man is the only animal that blushes.\n" +
+		"Or needs to.   \r\n" +
+		"\t\t-- Mark Twain, \"Following the Equator\"\n" +
+		"%\n" +
+		"%\n" +
+		"A fortune without an author.\n" +
+		"%\n" +
+		"-- Just an attribution\n"
+	rows, err := readAll(t, Fortune, input)
+	require.NoError(t, err)
+	require.Len(t, rows, 4, "empty fortunes are skipped")
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "The only true wisdom is in knowing you know nothing.", rows[0].Quote.Content)
+	assert.Equal(t, "Socrates", rows[0].Quote.Author)
+
+	assert.Equal(t, 6, rows[1].Line, "leading blank lines are skipped")
+	assert.Equal(t, "Man is the only animal that blushes.\nOr needs to.", rows[1].Quote.Content)
+	assert.Equal(t, "Mark Twain", rows[1].Quote.Author)
+	assert.Equal(t, "Following the Equator", rows[1].Quote.Source.WorkTitle)
+
+	assert.Equal(t, "A fortune without an author.", rows[2].Quote.Content)
+	assert.Empty(t, rows[2].Quote.Author)
+	assert.Equal(t, "-- Just an attribution", rows[3].Quote.Content, "a fortune is never only an attribution")
+}
+
+func TestFortuneWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewFortuneWriter(&out)
+	require.NoError(t, writer.Write("The only true wisdom is in knowing you know nothing.", "Socrates", ""))
+	require.NoError(t, writer.Write("Man is the only animal that blushes.  \nOr needs to.\n", "Mark  Twain", "Following the Equator"))
+	require.NoError(t, writer.Write("A line of\n%\non its own.", "", ""))
+
+	expected := "The only true wisdom is in knowing you know nothing.\n" +
+		"\t\t-- Socrates\n" +
+		"%\n" +
+		"Man is the only animal that blushes.\n" +
+		"Or needs to.\n" +
+		"\t\t-- Mark Twain, \"Following the Equator\"\n" +
+		"%\n" +
+		"A line of\n" +
+		" %\n" +
+		"on its own.\n" +
+		"%\n"
+	assert.Equal(t, expected, out.String())
+	assert.Equal(t, int64(len(expected)), writer.Len())
+
+	// Fortunes read back as written
+	rows, err := readAll(t, Fortune, out.String())
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "Mark Twain", rows[1].Quote.Author)
+	assert.Equal(t, "Following the Equator", rows[1].Quote.Source.WorkTitle)
+	assert.Equal(t, "A line of\n %\non its own.", rows[2].Quote.Content)
+
+	var index bytes.Buffer
+	require.NoError(t, writer.WriteIndex(&index))
+	assert.Equal(t, writer.IndexLen(), int64(index.Len()))
+
+	var header strfileHeader
+	require.NoError(t, binary.Read(&index, binary.BigEndian, &header))
+	first := len("The only true wisdom is in knowing you know nothing.\n\t\t-- Socrates\n")
+	second := len("Man is the only animal that blushes.\nOr needs to.\n\t\t-- Mark Twain, \"Following the Equator\"\n")
+	third := len("A line of\n %\non its own.\n")
+	assert.Equal(t, strfileHeader{
+		Version:  2,
+		NumStr:   3,
+		LongLen:  uint32(second),
+		ShortLen: uint32(third),
+		Delim:    [4]byte{'%'},
+	}, header)
+
+	// Offsets start each fortune, and the last one ends the file
+	offsets := make([]uint32, 4)
+	require.NoError(t, binary.Read(&index, binary.BigEndian, offsets))
+	assert.Equal(t, []uint32{0, uint32(first + 2), uint32(first + second + 4), uint32(len(expected))}, offsets)
+	for _, offset := range offsets[:3] {
+		assert.NotEqual(t, "%", strings.SplitN(expected[offset:], "\n", 2)[0])
+	}
+	assert.Zero(t, index.Len())
+}
+
+func TestEmptyFortuneIndex(t *testing.T) {
+	var index bytes.Buffer
+	writer := NewFortuneWriter(&bytes.Buffer{})
+	require.NoError(t, writer.WriteIndex(&index))
+	assert.Equal(t, 28, index.Len(), "the header and the end offset")
+}
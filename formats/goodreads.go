@@ -0,0 +1,133 @@
+package formats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// goodreadsColumns maps the column names quote exports from Goodreads use
+// to the fields they set. Other columns, like likes and tags, are ignored.
+var goodreadsColumns = map[string]string{
+	"quote":       "content",
+	"quote text":  "content",
+	"text":        "content",
+	"author":      "author",
+	"author name": "author",
+	"book":        "work",
+	"book title":  "work",
+	"title":       "work",
+	"work":        "work",
+}
+
+// goodreadsLibrary are columns of the Goodreads library export, which lists
+// books rather than quotes
+var goodreadsLibrary = []string{"exclusive shelf", "my rating", "bookshelves"}
+
+// goodreadsReader reads quotes exported from Goodreads as CSV. Goodreads
+// itself only exports books, so the columns of common quote exports are
+// accepted: the quote, its author and optionally its book. Rows are
+// numbered from 1 after the header.
+type goodreadsReader struct {
+	csv     *csv.Reader
+	columns map[string]int // Field to column
+	rows    int
+	err     error
+}
+
+func newGoodreadsReader(r io.Reader) *goodreadsReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true // Quotes often contain stray quotation marks
+	return &goodreadsReader{csv: reader}
+}
+
+func (r *goodreadsReader) readHeader() error {
+	header, err := r.csv.Read()
+	if err == io.EOF {
+		return &SyntaxError{Line: 1, Msg: "missing header"}
+	}
+	if err != nil {
+		return csvSyntaxError(err)
+	}
+
+	r.columns = map[string]int{}
+	names := map[string]bool{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		names[name] = true
+		if field, ok := goodreadsColumns[name]; ok {
+			if _, seen := r.columns[field]; !seen {
+				r.columns[field] = i
+			}
+		}
+	}
+
+	_, hasContent := r.columns["content"]
+	_, hasAuthor := r.columns["author"]
+	if !hasContent {
+		for _, column := range goodreadsLibrary {
+			if names[column] {
+				return &SyntaxError{Line: 1, Msg: "this is a Goodreads library export, which lists books, not quotes"}
+			}
+		}
+	}
+	if !hasContent || !hasAuthor {
+		return &SyntaxError{Line: 1, Msg: "expected a quote and an author column"}
+	}
+	return nil
+}
+
+func (r *goodreadsReader) Read() (Row, error) {
+	if r.err != nil {
+		return Row{}, r.err
+	}
+	if r.columns == nil {
+		if r.err = r.readHeader(); r.err != nil {
+			return Row{}, r.err
+		}
+	}
+
+	fields, err := r.csv.Read()
+	if err != nil {
+		if err != io.EOF {
+			err = csvSyntaxError(err)
+		}
+		r.err = err
+		return Row{}, err
+	}
+	r.rows++
+
+	row := Row{Line: r.rows, Problems: map[string]string{}}
+	field := func(name string) string {
+		if i, ok := r.columns[name]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+	if i := max(r.columns["content"], r.columns["author"]); i >= len(fields) {
+		row.Problems["row"] = fmt.Sprintf("has %d fields, expected at least %d", len(fields), i+1)
+		return row, nil
+	}
+
+	var rec record
+	rec.Content = unquote(field("content"))
+	rec.Author = strings.TrimRight(strings.TrimLeft(field("author"), "―—–- "), ", ")
+	rec.Source.WorkTitle = field("work")
+	row.Quote = rec.quote()
+	return row, nil
+}
+
+// unquote removes the quotation marks around a quote, unless they close
+// and open again inside it
+func unquote(text string) string {
+	for _, marks := range [][2]string{{"“", "”"}, {`"`, `"`}, {"«", "»"}} {
+		inner, opened := strings.CutPrefix(text, marks[0])
+		inner, closed := strings.CutSuffix(inner, marks[1])
+		if opened && closed && !strings.Contains(inner, marks[1]) {
+			return strings.TrimSpace(inner)
+		}
+	}
+	return text
+}
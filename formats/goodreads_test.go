@@ -0,0 +1,38 @@
+package formats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoodreadsReader(t *testing.T) {
+	input := "\ufeffQuote,Author,Book,Likes,Tags\n" +
+		"\"“So it goes.”\",\"― Kurt Vonnegut,\",Slaughterhouse-Five,1200,war\n" +
+		"\"\"\"Be yourself\"\", she said, \"\"everyone else is taken.\"\"\",Oscar Wilde,,10,\n" +
+		"Too short\n"
+	rows, err := readAll(t, Goodreads, input)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, "So it goes.", rows[0].Quote.Content)
+	assert.Equal(t, "Kurt Vonnegut", rows[0].Quote.Author)
+	assert.Equal(t, "Slaughterhouse-Five", rows[0].Quote.Source.WorkTitle)
+
+	assert.Equal(t, `"Be yourself", she said, "everyone else is taken."`, rows[1].Quote.Content,
+		"quotation marks inside the quote are kept")
+	assert.Equal(t, "has 1 fields, expected at least 2", rows[2].Problems["row"])
+}
+
+func TestGoodreadsHeader(t *testing.T) {
+	_, err := readAll(t, Goodreads, "Book Id,Title,Author,My Rating,Exclusive Shelf\n1,Dune,Frank Herbert,5,read\n")
+	var syntax *SyntaxError
+	require.ErrorAs(t, err, &syntax)
+	assert.Contains(t, syntax.Msg, "library export")
+
+	_, err = readAll(t, Goodreads, "Quote,Likes\nSo it goes.,3\n")
+	require.ErrorAs(t, err, &syntax)
+	assert.Equal(t, "expected a quote and an author column", syntax.Msg)
+}
@@ -0,0 +1,136 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxLineLength is the longest NDJSON line read
+const maxLineLength = 1 << 20
+
+// jsonReader reads a JSON array of quote objects without holding the whole
+// array in memory. Rows are numbered by their position in the array, from 1.
+type jsonReader struct {
+	decoder *json.Decoder
+	started bool
+	rows    int
+	err     error
+}
+
+func newJSONReader(r io.Reader) *jsonReader {
+	return &jsonReader{decoder: json.NewDecoder(r)}
+}
+
+func (r *jsonReader) Read() (Row, error) {
+	if r.err != nil {
+		return Row{}, r.err
+	}
+	if !r.started {
+		r.started = true
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			r.err = &SyntaxError{Line: 1, Msg: "expected an array of quotes"}
+			return Row{}, r.err
+		}
+		if err != nil {
+			r.err = r.syntaxError(err)
+			return Row{}, r.err
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			r.err = &SyntaxError{Line: 1, Msg: "expected an array of quotes"}
+			return Row{}, r.err
+		}
+	}
+
+	if !r.decoder.More() {
+		if _, err := r.decoder.Token(); err != nil {
+			r.err = r.syntaxError(err)
+			return Row{}, r.err
+		}
+		r.err = io.EOF
+		return Row{}, r.err
+	}
+
+	var raw json.RawMessage
+	if err := r.decoder.Decode(&raw); err != nil {
+		r.err = r.syntaxError(err)
+		return Row{}, r.err
+	}
+	r.rows++
+	return decodeRow(r.rows, raw), nil
+}
+
+// syntaxError reports which quote decoding stopped at. The decoder only
+// knows byte offsets, so no line is given.
+func (r *jsonReader) syntaxError(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) || err == io.EOF {
+		return &SyntaxError{Msg: "unexpected end of file"}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &SyntaxError{Msg: fmt.Sprintf("quote %d: %s", r.rows+1, syntaxErr)}
+	}
+	return err
+}
+
+// ndjsonReader reads one quote object per line. Blank lines are skipped and
+// rows are numbered by line.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	return &ndjsonReader{scanner: scanner}
+}
+
+func (r *ndjsonReader) Read() (Row, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return decodeRow(r.line, line), nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return Row{}, &SyntaxError{Line: r.line + 1, Msg: "line too long"}
+		}
+		return Row{}, err
+	}
+	return Row{}, io.EOF
+}
+
+// decodeRow decodes one quote object. Values of the wrong type are reported
+// against their field and the rest of the object is still read.
+func decodeRow(line int, data []byte) Row {
+	row := Row{Line: line, Problems: map[string]string{}}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		var syntaxErr *json.SyntaxError
+		var timeErr *time.ParseError
+		switch {
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			row.Problems[typeErr.Field] = "must be a " + typeErr.Type.String()
+		case errors.As(err, &typeErr):
+			row.Problems["row"] = "must be a quote object"
+		case errors.As(err, &syntaxErr):
+			row.Problems["row"] = "invalid JSON: " + syntaxErr.Error()
+		case errors.As(err, &timeErr):
+			row.Problems["publish_at"] = "must be an RFC 3339 time" // The only time field
+		default:
+			row.Problems["row"] = err.Error()
+		}
+	}
+	row.Quote = rec.quote()
+	return row
+}
@@ -0,0 +1,134 @@
+package formats
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// kindleSeparator is the line after each clipping
+const kindleSeparator = "=========="
+
+var (
+	// kindleBook splits "Title (Author)" at the last parenthesized group
+	kindleBook = regexp.MustCompile(`^(.*?)\s*\(([^()]*)\)\s*$`)
+	// kindlePage finds the page in "- Your Highlight on page 12 | ..."
+	kindlePage = regexp.MustCompile(`(?i)\bpage\s+([0-9]+|[ivxlcdm]+)\b`)
+)
+
+// kindleReader reads the "My Clippings.txt" file of Kindle e-readers.
+// Highlights become quotes of the book and its author. Bookmarks, notes and
+// highlights cut by the clipping limit are skipped. Rows are numbered by the
+// line they start on.
+type kindleReader struct {
+	scanner *bufio.Scanner
+	line    int
+	done    bool
+}
+
+func newKindleReader(r io.Reader) *kindleReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	return &kindleReader{scanner: scanner}
+}
+
+func (r *kindleReader) Read() (Row, error) {
+	for !r.done {
+		start := r.line + 1
+		var lines []string
+		for {
+			if !r.scanner.Scan() {
+				if err := r.scanner.Err(); err != nil {
+					if errors.Is(err, bufio.ErrTooLong) {
+						return Row{}, &SyntaxError{Line: r.line + 1, Msg: "line too long"}
+					}
+					return Row{}, err
+				}
+				r.done = true
+				break
+			}
+			r.line++
+			// Kindles start every clipping with a byte order mark
+			line := strings.TrimSpace(strings.ReplaceAll(r.scanner.Text(), "\ufeff", ""))
+			if line == kindleSeparator {
+				break
+			}
+			lines = append(lines, line)
+		}
+
+		for len(lines) > 0 && lines[0] == "" {
+			lines, start = lines[1:], start+1
+		}
+		if len(lines) > 0 {
+			return parseClipping(start, lines), nil
+		}
+	}
+	return Row{}, io.EOF
+}
+
+// parseClipping reads one clipping: the book, a line describing the
+// clipping, a blank line and the highlighted text
+func parseClipping(line int, lines []string) Row {
+	row := Row{Line: line, Problems: map[string]string{}}
+	if len(lines) < 2 || !strings.HasPrefix(lines[1], "- ") {
+		row.Problems["row"] = "is not a Kindle clipping"
+		return row
+	}
+
+	kind := strings.ToLower(lines[1])
+	switch {
+	case strings.Contains(kind, "highlight"):
+	case strings.Contains(kind, "bookmark"):
+		row.Skipped = "bookmark"
+		return row
+	case strings.Contains(kind, "note"):
+		row.Skipped = "note"
+		return row
+	default:
+		row.Skipped = "not a highlight"
+		return row
+	}
+
+	var rec record
+	rec.Content = strings.TrimSpace(strings.Join(lines[2:], "\n"))
+	switch {
+	case rec.Content == "":
+		row.Skipped = "empty highlight"
+		return row
+	case strings.HasPrefix(rec.Content, "<You have reached the clipping limit"):
+		row.Skipped = "clipping limit reached"
+		return row
+	}
+
+	rec.Source.WorkTitle = lines[0]
+	if book := kindleBook.FindStringSubmatch(lines[0]); book != nil {
+		rec.Source.WorkTitle = book[1]
+		// Books with several authors list them separated by semicolons
+		authors := strings.Split(book[2], ";")
+		for i, author := range authors {
+			authors[i] = personName(author)
+		}
+		rec.Author = strings.Join(authors, ", ")
+	}
+	if page := kindlePage.FindStringSubmatch(lines[1]); page != nil {
+		rec.Source.Page = page[1]
+	}
+	row.Quote = rec.quote()
+	return row
+}
+
+// nameSuffixes end names without being a first name, as in "King, Jr."
+var nameSuffixes = map[string]bool{"jr": true, "sr": true, "ii": true, "iii": true, "iv": true, "phd": true}
+
+// personName turns a name written "Twain, Mark" into "Mark Twain"
+func personName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	last, first, found := strings.Cut(name, ", ")
+	if !found || strings.Contains(first, ",") || first == "" || last == "" ||
+		nameSuffixes[strings.ToLower(strings.TrimSuffix(first, "."))] {
+		return name
+	}
+	return first + " " + last
+}
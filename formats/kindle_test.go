@@ -0,0 +1,66 @@
+package formats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindleReader(t *testing.T) {
+	input := "\ufeffMeditations (Marcus Aurelius)\r\n" +
+		"- Your Highlight on page 12 | location 180-181 | Added on Monday, 3 June 2024 21:04:05\r\n" +
+		"\r\n" +
+		"You have power over your mind - not outside events.\r\n" +
+		"==========\r\n" +
+		"\ufeffMeditations (Marcus Aurelius)\r\n" +
+		"- Your Bookmark on page 14 | location 201 | Added on Monday, 3 June 2024 21:10:00\r\n" +
+		"\r\n" +
+		"\r\n" +
+		"==========\r\n" +
+		"\ufeffMeditations (Marcus Aurelius)\r\n" +
+		"- Your Note on page 12 | location 181 | Added on Monday, 3 June 2024 21:05:00\r\n" +
+		"\r\n" +
+		"Reread this\r\n" +
+		"==========\r\n" +
+		"\ufeffThe Adventures of Tom Sawyer (Twain, Mark)\r\n" +
+		"- Your Highlight at location 1200-1202 | Added on Tuesday, 4 June 2024 08:00:00\r\n" +
+		"\r\n" +
+		"Work consists of whatever a body is obliged to do.\r\n" +
+		"==========\r\n" +
+		"\ufeffThe Elements of Style (William Strunk Jr.;E. B. White)\r\n" +
+		"- Your Highlight on page 23 | Added on Tuesday, 4 June 2024 09:00:00\r\n" +
+		"\r\n" +
+		"<You have reached the clipping limit for this item>\r\n" +
+		"==========\r\n" +
+		"Just a line\r\n" +
+		"==========\r\n"
+	rows, err := readAll(t, Kindle, input)
+	require.NoError(t, err)
+	require.Len(t, rows, 6)
+
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Empty(t, rows[0].Skipped)
+	assert.Equal(t, "You have power over your mind - not outside events.", rows[0].Quote.Content)
+	assert.Equal(t, "Marcus Aurelius", rows[0].Quote.Author)
+	assert.Equal(t, "Meditations", rows[0].Quote.Source.WorkTitle)
+	assert.Equal(t, "12", rows[0].Quote.Source.Page)
+
+	assert.Equal(t, 6, rows[1].Line)
+	assert.Equal(t, "bookmark", rows[1].Skipped)
+	assert.Equal(t, "note", rows[2].Skipped)
+
+	assert.Equal(t, "Mark Twain", rows[3].Quote.Author, "names are turned around")
+	assert.Equal(t, "The Adventures of Tom Sawyer", rows[3].Quote.Source.WorkTitle)
+	assert.Empty(t, rows[3].Quote.Source.Page)
+
+	assert.Equal(t, "clipping limit reached", rows[4].Skipped)
+	assert.Equal(t, "is not a Kindle clipping", rows[5].Problems["row"])
+}
+
+func TestPersonName(t *testing.T) {
+	assert.Equal(t, "Mark Twain", personName("Twain,  Mark"))
+	assert.Equal(t, "Marcus Aurelius", personName("Marcus Aurelius"))
+	assert.Equal(t, "Martin Luther King, Jr.", personName("Martin Luther King, Jr."))
+	assert.Equal(t, "Strunk, White, et al.", personName("Strunk, White, et al."))
+}
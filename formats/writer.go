@@ -0,0 +1,105 @@
+package formats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// Record is a row of an export. JSON and NDJSON write the record as JSON,
+// CSV writes its fields in the order of the header.
+type Record interface {
+	CSVFields() []string
+}
+
+// Writer writes records one at a time, so exports can be streamed without
+// holding them in memory
+type Writer struct {
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	count  int
+}
+
+// WriterNames lists the formats NewWriter can write. Fortune files are
+// written by a FortuneWriter.
+var WriterNames = []string{CSV, JSON, NDJSON}
+
+// NewWriter returns a writer for the named format. CSV files start with the
+// header.
+func NewWriter(format string, w io.Writer, header []string) (*Writer, error) {
+	writer := &Writer{format: format, w: w}
+	switch format {
+	case CSV:
+		writer.csv = csv.NewWriter(w)
+		if err := writer.csv.Write(header); err != nil {
+			return nil, err
+		}
+	case JSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return nil, err
+		}
+	case NDJSON:
+	default:
+		return nil, ErrUnknownFormat
+	}
+	return writer, nil
+}
+
+// ContentType returns the media type of a format
+func ContentType(format string) string {
+	switch format {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case NDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// Write writes one record
+func (w *Writer) Write(record Record) error {
+	w.count++
+	if w.format == CSV {
+		return w.csv.Write(record.CSVFields())
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	separator := "\n"
+	if w.format == JSON && w.count > 1 {
+		separator = ",\n"
+	}
+	if w.format == NDJSON {
+		data, separator = append(data, '\n'), ""
+	}
+	if _, err := io.WriteString(w.w, separator); err != nil {
+		return err
+	}
+	_, err = w.w.Write(data)
+	return err
+}
+
+// Flush writes any buffered CSV data to the underlying writer
+func (w *Writer) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// Close ends the file. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.format == JSON {
+		end := "\n]\n"
+		if w.count == 0 {
+			end = "]\n"
+		}
+		_, err := io.WriteString(w.w, end)
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// eloK is the maximum rating change of a single battle
+const eloK = 32
+
+// battleSamples is how many random pairs NextBattle tries before going
+// through the pairs in order
+const battleSamples = 20
+
+type BattleHandler struct {
+	db *gorm.DB
+}
+
+func NewBattleHandler(db *gorm.DB) *BattleHandler {
+	return &BattleHandler{db: db}
+}
+
+// BattleResponse is a battle with both contenders in display order
+type BattleResponse struct {
+	models.Battle
+	Quotes []models.Quote `json:"quotes"`
+}
+
+// RecordBattleInput is the body of a battle result
+type RecordBattleInput struct {
+	WinnerID uint `json:"winner_id" binding:"required"`
+}
+
+// eloUpdate returns the new ratings of a and b after a battle
+func eloUpdate(ratingA, ratingB float64, aWon bool) (float64, float64) {
+	expectedA := 1 / (1 + math.Pow(10, (ratingB-ratingA)/400))
+	scoreA := 0.0
+	if aWon {
+		scoreA = 1
+	}
+	change := eloK * (scoreA - expectedA)
+	return ratingA + change, ratingB - change
+}
+
+// newBattleResponse loads the contenders of a battle in random order so
+// neither side is favoured by its position
+func (h *BattleHandler) newBattleResponse(battle models.Battle) (BattleResponse, error) {
+	var quotes []models.Quote
+	if err := h.db.Where("id IN ?", []uint{battle.QuoteAID, battle.QuoteBID}).Find(&quotes).Error; err != nil {
+		return BattleResponse{}, err
+	}
+	rand.Shuffle(len(quotes), func(i, j int) { quotes[i], quotes[j] = quotes[j], quotes[i] })
+	return BattleResponse{Battle: battle, Quotes: quotes}, nil
+}
+
+// NextBattle serves two quotes the current user has not judged yet
+func (h *BattleHandler) NextBattle(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Serve the pending battle first so users cannot skip pairs. Battles
+	// whose quotes were hidden or removed since are dropped instead.
+	var pending models.Battle
+	err := h.db.Where("user_id = ? AND winner_id IS NULL", userID).First(&pending).Error
+	if err == nil {
+		var visible int64
+		err = h.db.Model(&models.Quote{}).Scopes(publiclyVisible).
+			Where("quotes.id IN ?", []uint{pending.QuoteAID, pending.QuoteBID}).Count(&visible).Error
+		if err == nil && visible == 2 {
+			response, err := h.newBattleResponse(pending)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load battle"})
+				return
+			}
+			c.JSON(http.StatusOK, response)
+			return
+		}
+		if err == nil {
+			err = h.db.Delete(&pending).Error
+		}
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load battle"})
+		return
+	}
+
+	var quoteIDs []uint
+	if err := h.db.Model(&models.Quote{}).Scopes(publiclyVisible).Pluck("id", &quoteIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load quotes"})
+		return
+	}
+	if len(quoteIDs) < 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not enough quotes for a battle"})
+		return
+	}
+
+	// Collect the pairs this user has already judged
+	var judged []models.Battle
+	if err := h.db.Select("quote_a_id", "quote_b_id").Where("user_id = ?", userID).Find(&judged).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load battles"})
+		return
+	}
+	seen := make(map[[2]uint]bool, len(judged))
+	for _, b := range judged {
+		seen[[2]uint{b.QuoteAID, b.QuoteBID}] = true
+	}
+
+	pair, found := pickBattlePair(quoteIDs, seen)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No new quote pairs left to judge"})
+		return
+	}
+	battle := &models.Battle{UserID: userID.(uint), QuoteAID: pair[0], QuoteBID: pair[1]}
+	if err := h.db.Create(battle).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create battle"})
+		return
+	}
+
+	response, err := h.newBattleResponse(*battle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load battle"})
+		return
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// pickBattlePair picks a random pair of quotes that has not been seen, lower
+// ID first. Random pairs are tried first, which finds one right away unless
+// most pairs were judged. Failing that, the pairs are gone through in a
+// random order, which passes at most every seen pair once.
+func pickBattlePair(quoteIDs []uint, seen map[[2]uint]bool) ([2]uint, bool) {
+	ordered := func(a, b uint) [2]uint {
+		if a > b {
+			a, b = b, a
+		}
+		return [2]uint{a, b}
+	}
+	for try := 0; try < battleSamples; try++ {
+		i, j := rand.Intn(len(quoteIDs)), rand.Intn(len(quoteIDs)-1)
+		if j >= i {
+			j++ // Never pair a quote with itself
+		}
+		if pair := ordered(quoteIDs[i], quoteIDs[j]); !seen[pair] {
+			return pair, true
+		}
+	}
+
+	rand.Shuffle(len(quoteIDs), func(i, j int) { quoteIDs[i], quoteIDs[j] = quoteIDs[j], quoteIDs[i] })
+	for i := range quoteIDs {
+		for j := i + 1; j < len(quoteIDs); j++ {
+			if pair := ordered(quoteIDs[i], quoteIDs[j]); !seen[pair] {
+				return pair, true
+			}
+		}
+	}
+	return [2]uint{}, false
+}
+
+// RecordBattle records the winner of a battle and updates both ratings
+func (h *BattleHandler) RecordBattle(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	battleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid battle ID"})
+		return
+	}
+
+	var input RecordBattleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Start a transaction
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Users can only judge their own battles
+	var battle models.Battle
+	if err := tx.Where("id = ? AND user_id = ?", battleID, userID).First(&battle).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Battle not found"})
+		return
+	}
+
+	if battle.WinnerID != nil {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Battle has already been judged"})
+		return
+	}
+
+	if input.WinnerID != battle.QuoteAID && input.WinnerID != battle.QuoteBID {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Winner must be one of the battle's quotes"})
+		return
+	}
+
+	var quoteA, quoteB models.Quote
+	if err := tx.First(&quoteA, battle.QuoteAID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+	if err := tx.First(&quoteB, battle.QuoteBID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	quoteA.Rating, quoteB.Rating = eloUpdate(quoteA.Rating, quoteB.Rating, input.WinnerID == quoteA.ID)
+	quoteA.Battles++
+	quoteB.Battles++
+	// Ratings are not content, so the version and updated time are kept.
+	// The battle count is part of the ETag instead.
+	if err := tx.Model(&quoteA).UpdateColumns(map[string]interface{}{"rating": quoteA.Rating, "battles": quoteA.Battles}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ratings"})
+		return
+	}
+	if err := tx.Model(&quoteB).UpdateColumns(map[string]interface{}{"rating": quoteB.Rating, "battles": quoteB.Battles}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ratings"})
+		return
+	}
+
+	now := time.Now()
+	battle.WinnerID = &input.WinnerID
+	battle.DecidedAt = &now
+	if err := tx.Save(&battle).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record battle"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process battle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Battle recorded successfully",
+		"battle":  battle,
+		"quotes":  []models.Quote{quoteA, quoteB},
+	})
+}
+
+// GetRankings returns public quotes ordered by battle rating
+func (h *BattleHandler) GetRankings(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	var quotes []models.Quote
+	if err := h.db.Scopes(publiclyVisible).Where("quotes.battles > 0").Order("rating desc").Order("battles desc").Limit(limit).Find(&quotes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load rankings"})
+		return
+	}
+
+	rankings := make([]gin.H, len(quotes))
+	for i, quote := range quotes {
+		rankings[i] = gin.H{"rank": i + 1, "quote": quote}
+	}
+
+	c.JSON(http.StatusOK, rankings)
+}
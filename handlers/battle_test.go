@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEloUpdate(t *testing.T) {
+	// Equal ratings move by half of K
+	a, b := eloUpdate(1500, 1500, true)
+	assert.InDelta(t, 1516, a, 0.001)
+	assert.InDelta(t, 1484, b, 0.001)
+
+	// An upset moves ratings more than an expected win
+	upsetA, _ := eloUpdate(1400, 1600, true)
+	expectedA, _ := eloUpdate(1600, 1400, true)
+	assert.Greater(t, upsetA-1400, expectedA-1600)
+}
+
+func TestQuoteBattle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	user := models.User{Username: "battleuser", Password: "hashed"}
+	db.Create(&user)
+	db.Create(&models.Quote{Content: "first", Author: "one"})
+	db.Create(&models.Quote{Content: "second", Author: "two"})
+
+	r := gin.Default()
+	battleHandler := NewBattleHandler(db)
+	withUser := func(h gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("user_id", user.ID)
+			h(c)
+		}
+	}
+	r.GET("/battles/next", withUser(battleHandler.NextBattle))
+	r.POST("/battles/:id", withUser(battleHandler.RecordBattle))
+	r.GET("/battles/rankings", battleHandler.GetRankings)
+
+	// Get a battle
+	w1 := httptest.NewRecorder()
+	req1, _ := http.NewRequest("GET", "/battles/next", nil)
+	r.ServeHTTP(w1, req1)
+	assert.Equal(t, http.StatusCreated, w1.Code)
+	var battle BattleResponse
+	json.Unmarshal(w1.Body.Bytes(), &battle)
+	assert.Len(t, battle.Quotes, 2)
+
+	// Asking again serves the same pending battle
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/battles/next", nil)
+	r.ServeHTTP(w2, req2)
+	assert.Equal(t, http.StatusOK, w2.Code)
+	var again BattleResponse
+	json.Unmarshal(w2.Body.Bytes(), &again)
+	assert.Equal(t, battle.ID, again.ID)
+
+	// Record the winner
+	winner := battle.Quotes[0].ID
+	body, _ := json.Marshal(RecordBattleInput{WinnerID: winner})
+	w3 := httptest.NewRecorder()
+	req3, _ := http.NewRequest("POST", fmt.Sprintf("/battles/%d", battle.ID), bytes.NewBuffer(body))
+	req3.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w3, req3)
+	assert.Equal(t, http.StatusOK, w3.Code)
+
+	var winnerQuote models.Quote
+	db.First(&winnerQuote, winner)
+	assert.InDelta(t, 1516, winnerQuote.Rating, 0.001)
+	assert.Equal(t, 1, winnerQuote.Battles)
+	assert.EqualValues(t, battle.Quotes[0].Version, winnerQuote.Version, "ratings are not content")
+	assert.Equal(t, battle.Quotes[0].UpdatedAt.Unix(), winnerQuote.UpdatedAt.Unix())
+
+	// The same battle cannot be judged twice
+	w4 := httptest.NewRecorder()
+	req4, _ := http.NewRequest("POST", fmt.Sprintf("/battles/%d", battle.ID), bytes.NewBuffer(body))
+	req4.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w4, req4)
+	assert.Equal(t, http.StatusConflict, w4.Code)
+
+	// With two quotes the only pair has been judged
+	w5 := httptest.NewRecorder()
+	req5, _ := http.NewRequest("GET", "/battles/next", nil)
+	r.ServeHTTP(w5, req5)
+	assert.Equal(t, http.StatusNotFound, w5.Code)
+
+	// Rankings list the winner first
+	w6 := httptest.NewRecorder()
+	req6, _ := http.NewRequest("GET", "/battles/rankings", nil)
+	r.ServeHTTP(w6, req6)
+	assert.Equal(t, http.StatusOK, w6.Code)
+	var rankings []struct {
+		Rank  int          `json:"rank"`
+		Quote models.Quote `json:"quote"`
+	}
+	json.Unmarshal(w6.Body.Bytes(), &rankings)
+	assert.Len(t, rankings, 2)
+	assert.Equal(t, winner, rankings[0].Quote.ID)
+}
+
+func TestNextBattleDropsStalePending(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	user := models.User{Username: "battleuser", Password: "hashed"}
+	db.Create(&user)
+	quotes := []models.Quote{{Content: "first", Author: "one"}, {Content: "second", Author: "two"}, {Content: "third", Author: "three"}}
+	db.Create(&quotes)
+	pending := models.Battle{UserID: user.ID, QuoteAID: quotes[0].ID, QuoteBID: quotes[1].ID}
+	db.Create(&pending)
+	db.Model(&quotes[0]).Update("hidden", true)
+
+	r := gin.Default()
+	r.GET("/battles/next", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		NewBattleHandler(db).NextBattle(c)
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/battles/next", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var battle BattleResponse
+	json.Unmarshal(w.Body.Bytes(), &battle)
+	assert.Equal(t, quotes[1].ID, battle.QuoteAID)
+	assert.Equal(t, quotes[2].ID, battle.QuoteBID)
+
+	var count int64
+	db.Model(&models.Battle{}).Count(&count)
+	assert.EqualValues(t, 1, count, "the stale battle is dropped")
+}
+
+func TestRankingsArePublic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	future := time.Now().Add(time.Hour)
+	quotes := []models.Quote{
+		{Content: "public", Author: "one", Rating: 1500, Battles: 1},
+		{Content: "hidden", Author: "two", Rating: 1600, Battles: 1, Hidden: true},
+		{Content: "scheduled", Author: "three", Rating: 1700, Battles: 1, PublishAt: &future},
+		{Content: "pending", Author: "four", Rating: 1800, Battles: 1, Status: models.QuoteStatusPending},
+	}
+	db.Create(&quotes)
+
+	r := gin.Default()
+	r.GET("/battles/rankings", NewBattleHandler(db).GetRankings)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/battles/rankings", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var rankings []struct {
+		Quote models.Quote `json:"quote"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &rankings)
+	require.Len(t, rankings, 1)
+	assert.Equal(t, quotes[0].ID, rankings[0].Quote.ID)
+}
+
+func TestPickBattlePair(t *testing.T) {
+	ids := []uint{1, 2, 3, 4, 5, 6}
+	seen := map[[2]uint]bool{}
+	for len(seen) < 15 {
+		pair, found := pickBattlePair(ids, seen)
+		require.True(t, found)
+		assert.Less(t, pair[0], pair[1])
+		assert.False(t, seen[pair], "pairs are not repeated")
+		seen[pair] = true
+	}
+	_, found := pickBattlePair(ids, seen)
+	assert.False(t, found)
+}
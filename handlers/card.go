@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"Qoute-backend/card"
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cardCache keeps rendered cards. Keys include the quote version, so edits
+// never serve a stale card and old versions age out.
+var cardCache = card.NewCache(512)
+
+// cardFormats maps card formats to their content types
+var cardFormats = map[string]string{
+	"png": "image/png",
+	"svg": "image/svg+xml",
+}
+
+// GetQuoteCardPNG renders a quote as a PNG image card
+func GetQuoteCardPNG(c *gin.Context) {
+	respondWithQuoteCard(c, "png")
+}
+
+// GetQuoteCardSVG renders a quote as an SVG image card
+func GetQuoteCardSVG(c *gin.Context) {
+	respondWithQuoteCard(c, "svg")
+}
+
+// respondWithQuoteCard renders a quote card in the theme and size given by
+// the query params, from the cache when the quote has not changed
+func respondWithQuoteCard(c *gin.Context, format string) {
+	themeName := c.DefaultQuery("theme", card.DefaultTheme)
+	theme, ok := card.Themes[themeName]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid theme", "themes": card.Names(card.Themes)})
+		return
+	}
+	sizeName := c.DefaultQuery("size", card.DefaultSize)
+	size, ok := card.Sizes[sizeName]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size", "sizes": card.Names(card.Sizes)})
+		return
+	}
+
+	viewer, err := loadQuoteViewer(c, config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var quote models.Quote
+	if err := config.DB.Scopes(viewer.visible).First(&quote, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	key := fmt.Sprintf("%d-%d-%s-%s.%s", quote.ID, quote.Version, themeName, sizeName, format)
+	c.Header("Vary", "Authorization")
+	c.Header("Cache-Control", "public, max-age=3600")
+	if notModified(c, `"card-`+key+`"`) {
+		return
+	}
+
+	data, ok := cardCache.Get(key)
+	if !ok {
+		var buf bytes.Buffer
+		render := card.PNG
+		if format == "svg" {
+			render = card.SVG
+		}
+		if err := render(&buf, card.Card{Text: quote.Content, Author: quote.Author, Theme: theme, Size: size}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render card"})
+			return
+		}
+		data = buf.Bytes()
+		cardCache.Add(key, data)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="quote-%d.%s"`, quote.ID, format))
+	c.Data(http.StatusOK, cardFormats[format], data)
+}
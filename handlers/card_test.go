@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteCards(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	quote := models.Quote{Content: "The unexamined life is not worth living.", Author: "Socrates"}
+	db.Create(&quote)
+	scheduled := models.Quote{Content: "Soon", Author: "someone", PublishAt: ptr(time.Now().Add(time.Hour))}
+	db.Create(&scheduled)
+
+	r := gin.Default()
+	r.GET("/quotes/:id/comments", func(c *gin.Context) {})
+	r.GET("/quotes/:id/card.png", GetQuoteCardPNG)
+	r.GET("/quotes/:id/card.svg", GetQuoteCardSVG)
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	cached := cardCache.Len()
+
+	w := get(fmt.Sprintf("/quotes/%d/card.png?size=square&theme=dark", quote.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 1080, img.Bounds().Dx())
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, cached+1, cardCache.Len())
+
+	// Repeated requests are served from the cache or revalidated
+	w = get(fmt.Sprintf("/quotes/%d/card.png?size=square&theme=dark", quote.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, cached+1, cardCache.Len())
+	w = get(fmt.Sprintf("/quotes/%d/card.png?size=square&theme=dark", quote.ID), map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// An edit renders a new card
+	db.Model(&quote).Updates(map[string]interface{}{"content": "Know thyself.", "version": bumpVersion})
+	w = get(fmt.Sprintf("/quotes/%d/card.png?size=square&theme=dark", quote.ID), map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	w = get(fmt.Sprintf("/quotes/%d/card.svg", quote.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "Know thyself.")
+	assert.Contains(t, w.Body.String(), `width="1200" height="630"`)
+
+	assert.Equal(t, http.StatusBadRequest, get(fmt.Sprintf("/quotes/%d/card.png?theme=neon", quote.ID), nil).Code)
+	assert.Equal(t, http.StatusBadRequest, get(fmt.Sprintf("/quotes/%d/card.svg?size=huge", quote.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, get(fmt.Sprintf("/quotes/%d/card.png", scheduled.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, get("/quotes/999/card.png", nil).Code)
+}
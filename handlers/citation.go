@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"Qoute-backend/models"
+)
+
+// Citation field limits
+const (
+	maxWorkTitleLength = 300
+	maxPageLength      = 20
+	maxURLLength       = 2048
+	maxLicenseLength   = 100
+	minCitationYear    = -3000
+)
+
+// validateCitation checks a citation and returns the problems by field name.
+// An empty map means the citation is valid.
+func validateCitation(citation models.Citation) map[string]string {
+	problems := map[string]string{}
+
+	if utf8.RuneCountInString(citation.WorkTitle) > maxWorkTitleLength {
+		problems["source.work_title"] = fmt.Sprintf("must be at most %d characters", maxWorkTitleLength)
+	}
+
+	if citation.Year != nil {
+		if year := *citation.Year; year < minCitationYear || year > time.Now().Year() {
+			problems["source.year"] = fmt.Sprintf("must be between %d and %d", minCitationYear, time.Now().Year())
+		}
+	}
+
+	if utf8.RuneCountInString(citation.Page) > maxPageLength {
+		problems["source.page"] = fmt.Sprintf("must be at most %d characters", maxPageLength)
+	}
+
+	if citation.URL != "" {
+		parsed, err := url.Parse(citation.URL)
+		switch {
+		case len(citation.URL) > maxURLLength:
+			problems["source.url"] = fmt.Sprintf("must be at most %d characters", maxURLLength)
+		case err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "":
+			problems["source.url"] = "must be an absolute http or https URL"
+		}
+	}
+
+	if utf8.RuneCountInString(citation.License) > maxLicenseLength {
+		problems["source.license"] = fmt.Sprintf("must be at most %d characters", maxLicenseLength)
+	}
+
+	switch citation.Verification {
+	case "", models.CitationUnverified, models.CitationVerified, models.CitationDisputed:
+	default:
+		problems["source.verification"] = "must be unverified, verified or disputed"
+	}
+
+	return problems
+}
+
+// settleCitationVerification decides the verification status of a citation
+// being saved. Only moderators choose it; anyone else editing the citation
+// resets it to unverified, and otherwise the previous status is kept.
+func settleCitationVerification(next *models.Citation, previous models.Citation, moderator bool) {
+	if moderator && next.Verification != "" {
+		return
+	}
+	if sameCitationSource(*next, previous) && previous.Verification != "" {
+		next.Verification = previous.Verification
+		return
+	}
+	next.Verification = models.CitationUnverified
+}
+
+// sameCitationSource compares two citations, ignoring their verification
+func sameCitationSource(a, b models.Citation) bool {
+	sameYear := (a.Year == nil && b.Year == nil) || (a.Year != nil && b.Year != nil && *a.Year == *b.Year)
+	return sameYear && a.WorkTitle == b.WorkTitle && a.Page == b.Page && a.URL == b.URL && a.License == b.License
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCitation(t *testing.T) {
+	year := 1605
+	assert.Empty(t, validateCitation(models.Citation{
+		WorkTitle: "Don Quixote",
+		Year:      &year,
+		Page:      "xii",
+		URL:       "https://www.gutenberg.org/ebooks/996",
+		License:   "public-domain",
+	}))
+
+	future := 3000
+	problems := validateCitation(models.Citation{
+		Year:         &future,
+		URL:          "javascript:alert(1)",
+		Verification: "probably",
+	})
+	assert.Contains(t, problems, "source.year")
+	assert.Contains(t, problems, "source.url")
+	assert.Contains(t, problems, "source.verification")
+}
+
+func TestSettleCitationVerification(t *testing.T) {
+	verified := models.Citation{WorkTitle: "Meditations", Verification: models.CitationVerified}
+
+	// Regular users cannot verify their own citations
+	next := models.Citation{WorkTitle: "Meditations", Verification: models.CitationVerified}
+	settleCitationVerification(&next, models.Citation{}, false)
+	assert.Equal(t, models.CitationUnverified, next.Verification)
+
+	// An unchanged citation keeps its status, an edited one is reset
+	next = models.Citation{WorkTitle: "Meditations"}
+	settleCitationVerification(&next, verified, false)
+	assert.Equal(t, models.CitationVerified, next.Verification)
+	next = models.Citation{WorkTitle: "Discourses"}
+	settleCitationVerification(&next, verified, false)
+	assert.Equal(t, models.CitationUnverified, next.Verification)
+
+	// Moderators decide
+	next = models.Citation{WorkTitle: "Discourses", Verification: models.CitationDisputed}
+	settleCitationVerification(&next, verified, true)
+	assert.Equal(t, models.CitationDisputed, next.Verification)
+}
+
+func TestCitationOnCreateAndFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	user := models.User{Username: "citer", Password: "hashed"}
+	db.Create(&user)
+	db.Create(&models.Quote{Content: "checked", Author: "a", Source: models.Citation{WorkTitle: "Book", Verification: models.CitationVerified}})
+
+	r := gin.Default()
+	r.POST("/quotes", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		CreateQuote(c)
+	})
+	r.GET("/quotes", GetQuotes)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"content": "unchecked",
+		"author":  "a",
+		"source":  map[string]interface{}{"work_title": "Pamphlet", "url": "ftp://example.com"},
+	})
+	req, _ := http.NewRequest("POST", "/quotes", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "source.url")
+
+	req, _ = http.NewRequest("GET", "/quotes?verified=true", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var response []QuoteResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Len(t, response, 1)
+	assert.Equal(t, "Book", response[0].Source.WorkTitle)
+}
+
+func TestVerifyCitation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	moderator := models.User{Username: "checker", Password: "hashed", Role: models.RoleModerator}
+	db.Create(&moderator)
+	quote := models.Quote{Content: "voted on", Author: "a", Source: models.Citation{WorkTitle: "Book"}}
+	db.Create(&quote)
+	db.Create(&models.Vote{QuoteID: quote.ID})
+
+	r := gin.Default()
+	r.PUT("/moderation/quotes/:id/verification", func(c *gin.Context) {
+		c.Set("user_id", moderator.ID)
+		NewModerationHandler(db).VerifyCitation(c)
+	})
+	verify := func(id uint, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/moderation/quotes/%d/verification", id), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Quotes with votes can still be verified
+	w := verify(quote.ID, `{"verification":"verified"}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated models.Quote
+	db.First(&updated, quote.ID)
+	assert.Equal(t, models.CitationVerified, updated.Source.Verification)
+	assert.Equal(t, "Book", updated.Source.WorkTitle)
+	assert.Equal(t, quote.Version+1, updated.Version)
+
+	assert.Equal(t, http.StatusBadRequest, verify(quote.ID, `{"verification":"probably"}`, nil).Code)
+	assert.Equal(t, http.StatusNotFound, verify(999, `{"verification":"disputed"}`, nil).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, verify(quote.ID, `{"verification":"disputed"}`, map[string]string{"If-Match": `"stale"`}).Code)
+}
@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CollectionHandler struct {
+	db *gorm.DB
+}
+
+func NewCollectionHandler(db *gorm.DB) *CollectionHandler {
+	return &CollectionHandler{db: db}
+}
+
+// CollectionInput is the body used to create or update a collection
+type CollectionInput struct {
+	Title       string `json:"title" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+	Visibility  string `json:"visibility"` // Defaults to private
+}
+
+// AddCollectionItemInput is the body used to add a quote to a collection
+type AddCollectionItemInput struct {
+	QuoteID uint `json:"quote_id" binding:"required"`
+}
+
+// ReorderCollectionInput lists quote IDs in their new order. Quotes that are
+// not listed keep their relative order after the listed ones.
+type ReorderCollectionInput struct {
+	QuoteIDs []uint `json:"quote_ids" binding:"required"`
+}
+
+// CollectionSummary is a collection in a listing
+type CollectionSummary struct {
+	models.Collection
+	ItemCount int `json:"item_count"`
+}
+
+// CollectionResponse is a collection with its quotes in order
+type CollectionResponse struct {
+	models.Collection
+	Quotes []QuoteResponse `json:"quotes"`
+}
+
+// isCollectionVisibility reports whether v is a known visibility
+func isCollectionVisibility(v string) bool {
+	switch v {
+	case models.CollectionVisibilityPublic, models.CollectionVisibilityPrivate, models.CollectionVisibilityUnlisted:
+		return true
+	}
+	return false
+}
+
+// newShareToken returns a random token for sharing unlisted collections
+func newShareToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// ensureDefaultCollection returns the user's Favourites collection, creating
+// it the first time it is needed. A unique index allows a single default
+// collection per user, so when two requests race to create it, the one that
+// loses loads the winner's.
+func ensureDefaultCollection(db *gorm.DB, userID uint) (models.Collection, error) {
+	var collection models.Collection
+	err := db.Where("user_id = ? AND is_default = ?", userID, true).First(&collection).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return collection, err
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return collection, err
+	}
+	collection = models.Collection{
+		UserID:     userID,
+		Title:      models.DefaultCollectionTitle,
+		Visibility: models.CollectionVisibilityPrivate,
+		IsDefault:  true,
+		ShareToken: token,
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&collection)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return collection, result.Error
+	}
+	collection = models.Collection{}
+	return collection, db.Where("user_id = ? AND is_default = ?", userID, true).First(&collection).Error
+}
+
+// ownedCollection loads the collection in the URL if it belongs to the
+// current user. It responds with 404 otherwise, so other users' private
+// collections are not revealed.
+func (h *CollectionHandler) ownedCollection(c *gin.Context) (models.Collection, bool) {
+	var collection models.Collection
+	if err := h.db.Where("user_id = ?", c.GetUint("user_id")).First(&collection, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		return collection, false
+	}
+	return collection, true
+}
+
+// summaries adds item counts to collections, counting only the quotes the
+// viewer may see. Share tokens are only left for the owner.
+func (h *CollectionHandler) summaries(collections []models.Collection, viewer quoteViewer) ([]CollectionSummary, error) {
+	ids := make([]uint, len(collections))
+	for i, collection := range collections {
+		ids[i] = collection.ID
+	}
+
+	var counts []struct {
+		CollectionID uint
+		Count        int
+	}
+	err := h.db.Model(&models.CollectionItem{}).
+		Select("collection_items.collection_id AS collection_id, COUNT(*) AS count").
+		Joins("JOIN quotes ON quotes.id = collection_items.quote_id AND quotes.deleted_at IS NULL").
+		Where("collection_items.collection_id IN ?", ids).
+		Scopes(viewer.visible).
+		Group("collection_items.collection_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	countByID := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		countByID[count.CollectionID] = count.Count
+	}
+
+	response := make([]CollectionSummary, len(collections))
+	for i, collection := range collections {
+		if !viewer.authenticated || viewer.userID != collection.UserID {
+			collection.ShareToken = ""
+		}
+		response[i] = CollectionSummary{Collection: collection, ItemCount: countByID[collection.ID]}
+	}
+	return response, nil
+}
+
+// respondWithCollection sends a collection with the quotes the viewer may see
+func (h *CollectionHandler) respondWithCollection(c *gin.Context, collection models.Collection, status int) {
+	viewer, err := loadQuoteViewer(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var items []models.CollectionItem
+	if err := h.db.Where("collection_id = ?", collection.ID).Order("position asc, id asc").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	quoteIDs := make([]uint, len(items))
+	for i, item := range items {
+		quoteIDs[i] = item.QuoteID
+	}
+
+	var quotes []models.Quote
+	if err := h.db.Scopes(viewer.visible, withResponseRelations).Where("quotes.id IN ?", quoteIDs).Find(&quotes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	quoteByID := make(map[uint]models.Quote, len(quotes))
+	for _, quote := range quotes {
+		quoteByID[quote.ID] = quote
+	}
+
+	response := CollectionResponse{Collection: collection, Quotes: []QuoteResponse{}}
+	if !viewer.authenticated || viewer.userID != collection.UserID {
+		response.ShareToken = ""
+	}
+	for _, item := range items {
+		if quote, ok := quoteByID[item.QuoteID]; ok {
+			response.Quotes = append(response.Quotes, viewer.response(quote))
+		}
+	}
+	c.JSON(status, response)
+}
+
+// GetMyCollections lists the current user's collections, Favourites first
+func (h *CollectionHandler) GetMyCollections(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	if _, err := ensureDefaultCollection(h.db, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load collections"})
+		return
+	}
+
+	var collections []models.Collection
+	if err := h.db.Where("user_id = ?", userID).Order("is_default desc, created_at asc").Find(&collections).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load collections"})
+		return
+	}
+
+	viewer, err := loadQuoteViewer(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load collections"})
+		return
+	}
+	response, err := h.summaries(collections, viewer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load collections"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetUserCollections lists another user's public collections
+func (h *CollectionHandler) GetUserCollections(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var collections []models.Collection
+	if err := h.db.Where("user_id = ? AND visibility = ?", userID, models.CollectionVisibilityPublic).Order("is_default desc, created_at asc").Find(&collections).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load collections"})
+		return
+	}
+
+	viewer, err := loadQuoteViewer(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load collections"})
+		return
+	}
+	response, err := h.summaries(collections, viewer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load collections"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCollection returns a public collection, or one of the current user's
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	var collection models.Collection
+	if err := h.db.First(&collection, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		return
+	}
+
+	userID, authenticated := c.Get("user_id")
+	owner := authenticated && userID.(uint) == collection.UserID
+	if !owner && collection.Visibility != models.CollectionVisibilityPublic {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		return
+	}
+
+	h.respondWithCollection(c, collection, http.StatusOK)
+}
+
+// GetSharedCollection returns an unlisted or public collection by its share
+// token
+func (h *CollectionHandler) GetSharedCollection(c *gin.Context) {
+	var collection models.Collection
+	err := h.db.Where("share_token = ? AND visibility IN ?", c.Param("token"),
+		[]string{models.CollectionVisibilityUnlisted, models.CollectionVisibilityPublic}).First(&collection).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		return
+	}
+
+	h.respondWithCollection(c, collection, http.StatusOK)
+}
+
+// CreateCollection creates a collection for the current user
+func (h *CollectionHandler) CreateCollection(c *gin.Context) {
+	var input CollectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Visibility == "" {
+		input.Visibility = models.CollectionVisibilityPrivate
+	}
+	if !isCollectionVisibility(input.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public, private or unlisted"})
+		return
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create collection"})
+		return
+	}
+	collection := models.Collection{
+		UserID:      c.GetUint("user_id"),
+		Title:       input.Title,
+		Description: input.Description,
+		Visibility:  input.Visibility,
+		ShareToken:  token,
+	}
+	if err := h.db.Create(&collection).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create collection"})
+		return
+	}
+
+	h.respondWithCollection(c, collection, http.StatusCreated)
+}
+
+// UpdateCollection changes the title, description and visibility of one of
+// the current user's collections
+func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
+	collection, ok := h.ownedCollection(c)
+	if !ok {
+		return
+	}
+
+	var input CollectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Visibility == "" {
+		input.Visibility = collection.Visibility
+	}
+	if !isCollectionVisibility(input.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public, private or unlisted"})
+		return
+	}
+
+	collection.Title = input.Title
+	collection.Description = input.Description
+	collection.Visibility = input.Visibility
+	if err := h.db.Model(&collection).Select("title", "description", "visibility").Updates(&collection).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update collection"})
+		return
+	}
+
+	h.respondWithCollection(c, collection, http.StatusOK)
+}
+
+// DeleteCollection deletes one of the current user's collections. The
+// Favourites collection cannot be deleted.
+func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
+	collection, ok := h.ownedCollection(c)
+	if !ok {
+		return
+	}
+	if collection.IsDefault {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The default collection cannot be deleted"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&collection).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete collection"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collection deleted successfully"})
+}
+
+// AddCollectionItem appends a quote to one of the current user's collections
+func (h *CollectionHandler) AddCollectionItem(c *gin.Context) {
+	collection, ok := h.ownedCollection(c)
+	if !ok {
+		return
+	}
+
+	var input AddCollectionItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.addItem(c, collection, input.QuoteID)
+}
+
+// RemoveCollectionItem removes a quote from one of the current user's
+// collections
+func (h *CollectionHandler) RemoveCollectionItem(c *gin.Context) {
+	collection, ok := h.ownedCollection(c)
+	if !ok {
+		return
+	}
+
+	h.removeItem(c, collection, c.Param("quote_id"))
+}
+
+// ReorderCollection changes the order of the quotes in one of the current
+// user's collections
+func (h *CollectionHandler) ReorderCollection(c *gin.Context) {
+	collection, ok := h.ownedCollection(c)
+	if !ok {
+		return
+	}
+
+	var input ReorderCollectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var items []models.CollectionItem
+	if err := h.db.Where("collection_id = ?", collection.ID).Order("position asc, id asc").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder collection"})
+		return
+	}
+	itemByQuote := make(map[uint]models.CollectionItem, len(items))
+	for _, item := range items {
+		itemByQuote[item.QuoteID] = item
+	}
+
+	// Listed quotes come first, in the requested order
+	ordered := make([]models.CollectionItem, 0, len(items))
+	listed := make(map[uint]bool, len(input.QuoteIDs))
+	for _, quoteID := range input.QuoteIDs {
+		item, ok := itemByQuote[quoteID]
+		if !ok || listed[quoteID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quote_ids must be distinct quotes of the collection", "quote_id": quoteID})
+			return
+		}
+		listed[quoteID] = true
+		ordered = append(ordered, item)
+	}
+	for _, item := range items {
+		if !listed[item.QuoteID] {
+			ordered = append(ordered, item)
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for position, item := range ordered {
+			if err := tx.Model(&item).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder collection"})
+		return
+	}
+
+	h.respondWithCollection(c, collection, http.StatusOK)
+}
+
+// AddFavourite adds the quote in the URL to the current user's Favourites
+func (h *CollectionHandler) AddFavourite(c *gin.Context) {
+	quoteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quote ID"})
+		return
+	}
+
+	collection, err := ensureDefaultCollection(h.db, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load favourites"})
+		return
+	}
+
+	h.addItem(c, collection, uint(quoteID))
+}
+
+// RemoveFavourite removes the quote in the URL from the current user's
+// Favourites
+func (h *CollectionHandler) RemoveFavourite(c *gin.Context) {
+	collection, err := ensureDefaultCollection(h.db, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load favourites"})
+		return
+	}
+
+	h.removeItem(c, collection, c.Param("id"))
+}
+
+// addItem appends a quote the current user can see to a collection
+func (h *CollectionHandler) addItem(c *gin.Context, collection models.Collection, quoteID uint) {
+	viewer, err := loadQuoteViewer(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.Scopes(viewer.visible).First(&quote, quoteID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	var existing int64
+	h.db.Model(&models.CollectionItem{}).Where("collection_id = ? AND quote_id = ?", collection.ID, quote.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Quote is already in this collection"})
+		return
+	}
+
+	var last struct{ Position *int }
+	if err := h.db.Model(&models.CollectionItem{}).Select("MAX(position) AS position").Where("collection_id = ?", collection.ID).Scan(&last).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add quote"})
+		return
+	}
+	item := models.CollectionItem{CollectionID: collection.ID, QuoteID: quote.ID}
+	if last.Position != nil {
+		item.Position = *last.Position + 1
+	}
+	if err := h.db.Create(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add quote"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+// removeItem removes a quote from a collection
+func (h *CollectionHandler) removeItem(c *gin.Context, collection models.Collection, quoteID string) {
+	result := h.db.Where("collection_id = ? AND quote_id = ?", collection.ID, quoteID).Delete(&models.CollectionItem{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove quote"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote is not in this collection"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quote removed from collection"})
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollections(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	users := []models.User{{Username: "collector", Password: "x"}, {Username: "browser", Password: "x"}}
+	db.Create(&users)
+	owner, other := users[0], users[1]
+	quotes := []models.Quote{{Content: "one", Author: "a"}, {Content: "two", Author: "b"}, {Content: "three", Author: "c"}}
+	db.Create(&quotes)
+
+	h := NewCollectionHandler(db)
+	r := gin.Default()
+	// Requests are sent as the user in the as query param, if any
+	r.Use(func(c *gin.Context) {
+		if as, err := strconv.ParseUint(c.Query("as"), 10, 32); err == nil {
+			c.Set("user_id", uint(as))
+		}
+	})
+	r.GET("/collections", h.GetMyCollections)
+	r.POST("/collections", h.CreateCollection)
+	r.GET("/collections/:id", h.GetCollection)
+	r.GET("/collections/shared/:token", h.GetSharedCollection)
+	r.PUT("/collections/:id", h.UpdateCollection)
+	r.DELETE("/collections/:id", h.DeleteCollection)
+	r.POST("/collections/:id/items", h.AddCollectionItem)
+	r.PUT("/collections/:id/items", h.ReorderCollection)
+	r.DELETE("/collections/:id/items/:quote_id", h.RemoveCollectionItem)
+	r.GET("/users/:id/collections", h.GetUserCollections)
+	r.POST("/quotes/:id/favourite", h.AddFavourite)
+
+	send := func(method, path string, user models.User, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		if user.ID != 0 {
+			path += fmt.Sprintf("?as=%d", user.ID)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Favourites is created on first use
+	w := send("POST", fmt.Sprintf("/quotes/%d/favourite", quotes[0].ID), owner, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = send("POST", fmt.Sprintf("/quotes/%d/favourite", quotes[0].ID), owner, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var mine []CollectionSummary
+	json.Unmarshal(send("GET", "/collections", owner, nil).Body.Bytes(), &mine)
+	assert.Len(t, mine, 1)
+	assert.True(t, mine[0].IsDefault)
+	assert.Equal(t, models.DefaultCollectionTitle, mine[0].Title)
+	assert.Equal(t, 1, mine[0].ItemCount)
+
+	w = send("DELETE", fmt.Sprintf("/collections/%d", mine[0].ID), owner, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Create and fill a collection
+	w = send("POST", "/collections", owner, CollectionInput{Title: "Stoics", Visibility: "secret"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = send("POST", "/collections", owner, CollectionInput{Title: "Stoics"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var collection CollectionResponse
+	json.Unmarshal(w.Body.Bytes(), &collection)
+	assert.Equal(t, models.CollectionVisibilityPrivate, collection.Visibility)
+	assert.NotEmpty(t, collection.ShareToken)
+
+	itemsPath := fmt.Sprintf("/collections/%d/items", collection.ID)
+	for _, quote := range quotes {
+		w = send("POST", itemsPath, owner, AddCollectionItemInput{QuoteID: quote.ID})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+	w = send("POST", itemsPath, other, AddCollectionItemInput{QuoteID: quotes[0].ID})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Reorder: listed quotes first, the rest keep their order
+	w = send("PUT", itemsPath, owner, ReorderCollectionInput{QuoteIDs: []uint{quotes[2].ID}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &collection)
+	contents := []string{}
+	for _, quote := range collection.Quotes {
+		contents = append(contents, quote.Content)
+	}
+	assert.Equal(t, []string{"three", "one", "two"}, contents)
+
+	w = send("PUT", itemsPath, owner, ReorderCollectionInput{QuoteIDs: []uint{quotes[0].ID, quotes[0].ID}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send("DELETE", fmt.Sprintf("%s/%d", itemsPath, quotes[1].ID), owner, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Private collections are hidden from others, unlisted ones are shared by token
+	collectionPath := fmt.Sprintf("/collections/%d", collection.ID)
+	assert.Equal(t, http.StatusNotFound, send("GET", collectionPath, other, nil).Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/collections/shared/"+collection.ShareToken, models.User{}, nil).Code)
+
+	w = send("PUT", collectionPath, owner, CollectionInput{Title: "Stoics", Visibility: models.CollectionVisibilityUnlisted})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", collectionPath, other, nil).Code)
+	w = send("GET", "/collections/shared/"+collection.ShareToken, models.User{}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var shared CollectionResponse
+	json.Unmarshal(w.Body.Bytes(), &shared)
+	assert.Len(t, shared.Quotes, 2)
+	assert.Empty(t, shared.ShareToken)
+
+	send("PUT", collectionPath, owner, CollectionInput{Title: "Stoics", Visibility: models.CollectionVisibilityPublic})
+	assert.Equal(t, http.StatusOK, send("GET", collectionPath, other, nil).Code)
+	var public []CollectionSummary
+	json.Unmarshal(send("GET", fmt.Sprintf("/users/%d/collections", owner.ID), models.User{}, nil).Body.Bytes(), &public)
+	assert.Len(t, public, 1)
+	assert.Equal(t, 2, public[0].ItemCount)
+
+	// Counts only include the quotes the viewer can see
+	pending := models.Quote{Content: "four", Author: "d", UserID: &owner.ID, Status: models.QuoteStatusPending}
+	db.Create(&pending)
+	assert.Equal(t, http.StatusCreated, send("POST", itemsPath, owner, AddCollectionItemInput{QuoteID: pending.ID}).Code)
+	json.Unmarshal(send("GET", fmt.Sprintf("/users/%d/collections", owner.ID), other, nil).Body.Bytes(), &public)
+	assert.Equal(t, 2, public[0].ItemCount)
+	json.Unmarshal(send("GET", "/collections", owner, nil).Body.Bytes(), &mine)
+	assert.Equal(t, 3, mine[1].ItemCount)
+
+	// Others cannot change or delete it
+	assert.Equal(t, http.StatusNotFound, send("DELETE", collectionPath, other, nil).Code)
+	assert.Equal(t, http.StatusOK, send("DELETE", collectionPath, owner, nil).Code)
+	var items int64
+	db.Model(&models.CollectionItem{}).Where("collection_id = ?", collection.ID).Count(&items)
+	assert.Zero(t, items)
+}
+
+func TestDefaultCollectionIsUnique(t *testing.T) {
+	setupInMemoryDB()
+	db := config.DB
+	user := models.User{Username: "collector", Password: "x"}
+	db.Create(&user)
+
+	first, err := ensureDefaultCollection(db, user.ID)
+	require.NoError(t, err)
+	again, err := ensureDefaultCollection(db, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+
+	// The database refuses a second default, as when two requests race
+	duplicate := models.Collection{UserID: user.ID, Title: "Favourites", IsDefault: true, ShareToken: "other"}
+	assert.Error(t, db.Create(&duplicate).Error)
+	assert.NoError(t, db.Create(&models.Collection{UserID: user.ID, Title: "Stoics", ShareToken: "stoics"}).Error)
+	var defaults int64
+	db.Model(&models.Collection{}).Where("user_id = ? AND is_default = ?", user.ID, true).Count(&defaults)
+	assert.EqualValues(t, 1, defaults)
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Comment pagination limits
+const (
+	defaultCommentPageSize = 20
+	maxCommentPageSize     = 100
+)
+
+type CommentHandler struct {
+	db *gorm.DB
+}
+
+func NewCommentHandler(db *gorm.DB) *CommentHandler {
+	return &CommentHandler{db: db}
+}
+
+// CreateCommentInput is the body of a new comment or reply
+type CreateCommentInput struct {
+	Content  string `json:"content" binding:"required,max=2000"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// UpdateCommentInput is the body used to edit a comment
+type UpdateCommentInput struct {
+	Content string `json:"content" binding:"required,max=2000"`
+}
+
+// CommentResponse is a comment with its replies. Deleted comments that
+// still have replies are shown as placeholders without content or author.
+type CommentResponse struct {
+	models.Comment
+	Deleted bool              `json:"deleted"`
+	Replies []CommentResponse `json:"replies,omitempty"`
+}
+
+// commentResponse hides the content and author of a deleted comment
+func commentResponse(comment models.Comment) CommentResponse {
+	response := CommentResponse{Comment: comment}
+	if comment.DeletedAt.Valid {
+		response.Deleted = true
+		response.Content = models.DeletedCommentPlaceholder
+		response.UserID = 0
+		response.User = nil
+		response.EditedAt = nil
+	}
+	return response
+}
+
+// shownThreads is a scope selecting the top level comments of a quote that
+// are shown: live ones and deleted ones that still have live replies
+func shownThreads(quoteID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Where("comments.quote_id = ? AND comments.parent_id IS NULL", quoteID).
+			Where("comments.deleted_at IS NULL OR EXISTS (SELECT 1 FROM comments replies WHERE replies.parent_id = comments.id AND replies.deleted_at IS NULL)")
+	}
+}
+
+// GetComments lists a quote's comment threads, oldest first, with page and
+// limit query params. Replies are included with their thread.
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	viewer, err := loadQuoteViewer(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.Scopes(viewer.visible).First(&quote, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive integer"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultCommentPageSize)))
+	if err != nil || limit < 1 || limit > maxCommentPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	var total int64
+	if err := h.db.Model(&models.Comment{}).Scopes(shownThreads(quote.ID)).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load comments"})
+		return
+	}
+
+	var threads []models.Comment
+	err = h.db.Scopes(shownThreads(quote.ID)).Preload("User").
+		Order("comments.created_at asc, comments.id asc").
+		Offset((page - 1) * limit).Limit(limit).Find(&threads).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load comments"})
+		return
+	}
+
+	threadIDs := make([]uint, len(threads))
+	for i, thread := range threads {
+		threadIDs[i] = thread.ID
+	}
+	var replies []models.Comment
+	if err := h.db.Preload("User").Where("parent_id IN ?", threadIDs).Order("created_at asc, id asc").Find(&replies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load comments"})
+		return
+	}
+	repliesByThread := map[uint][]CommentResponse{}
+	for _, reply := range replies {
+		repliesByThread[*reply.ParentID] = append(repliesByThread[*reply.ParentID], commentResponse(reply))
+	}
+
+	comments := make([]CommentResponse, len(threads))
+	for i, thread := range threads {
+		comments[i] = commentResponse(thread)
+		comments[i].Replies = repliesByThread[thread.ID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments": comments,
+		"page":     page,
+		"limit":    limit,
+		"total":    total,
+	})
+}
+
+// CreateComment comments on a quote, or replies to a top level comment
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	viewer, err := loadQuoteViewer(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.Scopes(viewer.visible).First(&quote, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	var input CreateCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input.Content = strings.TrimSpace(input.Content)
+	if input.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment cannot be empty"})
+		return
+	}
+
+	if input.ParentID != nil {
+		var parent models.Comment
+		if err := h.db.Where("quote_id = ?", quote.ID).First(&parent, *input.ParentID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+			return
+		}
+		if parent.ParentID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Replies can only be made to top level comments"})
+			return
+		}
+	}
+
+	comment := models.Comment{
+		QuoteID:  quote.ID,
+		UserID:   viewer.userID,
+		ParentID: input.ParentID,
+		Content:  input.Content,
+	}
+	if err := h.db.Create(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+	h.db.Preload("User").First(&comment, comment.ID)
+
+	c.JSON(http.StatusCreated, commentResponse(comment))
+}
+
+// UpdateComment lets the author edit their comment
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	var comment models.Comment
+	if err := h.db.First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if comment.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own comments"})
+		return
+	}
+
+	var input UpdateCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input.Content = strings.TrimSpace(input.Content)
+	if input.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment cannot be empty"})
+		return
+	}
+
+	now := time.Now()
+	comment.Content = input.Content
+	comment.EditedAt = &now
+	if err := h.db.Model(&comment).Select("content", "edited_at").Updates(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+	h.db.Preload("User").First(&comment, comment.ID)
+
+	c.JSON(http.StatusOK, commentResponse(comment))
+}
+
+// DeleteComment soft deletes a comment. Authors can delete their own
+// comments and moderators can delete any.
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	var comment models.Comment
+	if err := h.db.First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if comment.UserID != userID {
+		var user models.User
+		if err := h.db.Select("role").First(&user, userID).Error; err != nil || !isModeratorRole(user.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own comments"})
+			return
+		}
+	}
+
+	if err := h.db.Delete(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
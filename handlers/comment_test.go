@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type commentPage struct {
+	Comments []CommentResponse `json:"comments"`
+	Total    int               `json:"total"`
+}
+
+func TestCommentThreads(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	users := []models.User{
+		{Username: "commenter", Password: "x"},
+		{Username: "replier", Password: "x"},
+		{Username: "comment_mod", Password: "x", Role: models.RoleModerator},
+	}
+	db.Create(&users)
+	author, replier, moderator := users[0], users[1], users[2]
+	quote := models.Quote{Content: "discussed", Author: "a"}
+	db.Create(&quote)
+
+	h := NewCommentHandler(db)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		if as, err := strconv.ParseUint(c.Query("as"), 10, 32); err == nil {
+			c.Set("user_id", uint(as))
+		}
+	})
+	r.GET("/quotes/:id/comments", h.GetComments)
+	r.POST("/quotes/:id/comments", h.CreateComment)
+	r.PUT("/comments/:id", h.UpdateComment)
+	r.DELETE("/comments/:id", h.DeleteComment)
+	r.GET("/quotes/:id", GetQuote)
+
+	send := func(method, path string, user models.User, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, fmt.Sprintf("%s?as=%d", path, user.ID), bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	commentsPath := fmt.Sprintf("/quotes/%d/comments", quote.ID)
+	post := func(user models.User, input CreateCommentInput) CommentResponse {
+		w := send("POST", commentsPath, user, input)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var comment CommentResponse
+		json.Unmarshal(w.Body.Bytes(), &comment)
+		return comment
+	}
+
+	first := post(author, CreateCommentInput{Content: "First"})
+	assert.Equal(t, "commenter", first.User.Username)
+	second := post(author, CreateCommentInput{Content: "Second"})
+	reply := post(replier, CreateCommentInput{Content: "Reply", ParentID: &first.ID})
+
+	// Only one level of replies
+	w := send("POST", commentsPath, author, CreateCommentInput{Content: "Nested", ParentID: &reply.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = send("POST", commentsPath, author, CreateCommentInput{Content: "   "})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Only the author can edit
+	w = send("PUT", fmt.Sprintf("/comments/%d", first.ID), replier, UpdateCommentInput{Content: "Hijacked"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = send("PUT", fmt.Sprintf("/comments/%d", first.ID), author, UpdateCommentInput{Content: "First, edited"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var edited CommentResponse
+	json.Unmarshal(w.Body.Bytes(), &edited)
+	assert.Equal(t, "First, edited", edited.Content)
+	assert.NotNil(t, edited.EditedAt)
+
+	// Comment counts are part of quote responses
+	var quoteResponse QuoteResponse
+	json.Unmarshal(send("GET", fmt.Sprintf("/quotes/%d", quote.ID), author, nil).Body.Bytes(), &quoteResponse)
+	assert.Equal(t, 3, quoteResponse.CommentCount)
+
+	// Deleted threads with replies become placeholders, others disappear
+	assert.Equal(t, http.StatusForbidden, send("DELETE", fmt.Sprintf("/comments/%d", first.ID), replier, nil).Code)
+	assert.Equal(t, http.StatusOK, send("DELETE", fmt.Sprintf("/comments/%d", first.ID), author, nil).Code)
+	assert.Equal(t, http.StatusOK, send("DELETE", fmt.Sprintf("/comments/%d", second.ID), moderator, nil).Code)
+
+	var page commentPage
+	json.Unmarshal(send("GET", commentsPath, replier, nil).Body.Bytes(), &page)
+	assert.Equal(t, 1, page.Total)
+	assert.Len(t, page.Comments, 1)
+	assert.True(t, page.Comments[0].Deleted)
+	assert.Equal(t, models.DeletedCommentPlaceholder, page.Comments[0].Content)
+	assert.Nil(t, page.Comments[0].User)
+	assert.Len(t, page.Comments[0].Replies, 1)
+	assert.Equal(t, "Reply", page.Comments[0].Replies[0].Content)
+
+	// Replies cannot be added to deleted comments
+	w = send("POST", commentsPath, author, CreateCommentInput{Content: "Late", ParentID: &first.ID})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Once the last reply is gone the thread disappears
+	send("DELETE", fmt.Sprintf("/comments/%d", reply.ID), replier, nil)
+	json.Unmarshal(send("GET", commentsPath, replier, nil).Body.Bytes(), &page)
+	assert.Zero(t, page.Total)
+	assert.Empty(t, page.Comments)
+}
+
+func TestCommentPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	user := models.User{Username: "chatty", Password: "x"}
+	db.Create(&user)
+	quote := models.Quote{Content: "popular", Author: "a"}
+	db.Create(&quote)
+	for i := 0; i < 5; i++ {
+		db.Create(&models.Comment{QuoteID: quote.ID, UserID: user.ID, Content: fmt.Sprintf("comment %d", i)})
+	}
+
+	r := gin.Default()
+	r.GET("/quotes/:id/comments", NewCommentHandler(db).GetComments)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/quotes/%d/comments?%s", quote.ID, query), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	var page commentPage
+	json.Unmarshal(get("page=2&limit=2").Body.Bytes(), &page)
+	assert.Equal(t, 5, page.Total)
+	assert.Len(t, page.Comments, 2)
+	assert.Equal(t, "comment 2", page.Comments[0].Content)
+
+	assert.Equal(t, http.StatusBadRequest, get("limit=500").Code)
+}
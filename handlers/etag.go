@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bumpVersion is the update expression recording a change to a quote. Every
+// update of a quote row must include it so ETags change.
+var bumpVersion = gorm.Expr("version + 1")
+
+// quoteETag returns the strong entity tag of a quote loaded
+// withResponseRelations. It changes with the row version, with the vote
+// and comment counts shown alongside the quote, and with its battle count,
+// which changes whenever its rating does.
+func quoteETag(quote models.Quote) string {
+	return fmt.Sprintf(`"%d-%d-%d-%d-%d"`, quote.ID, quote.Version, len(quote.Votes), len(quote.Comments), quote.Battles)
+}
+
+// bodyETag returns a strong entity tag for a response body
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagListed reports whether an If-Match or If-None-Match header lists etag.
+// A "*" matches any entity tag. With weak comparison, as If-None-Match uses,
+// the W/ prefix is ignored.
+func etagListed(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag header and, when the request's If-None-Match
+// lists it, responds with 304 Not Modified and returns true
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	if header := c.GetHeader("If-None-Match"); header != "" && etagListed(header, etag, true) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// preconditionFailed checks the request's If-Match header against the quote
+// and responds with 412 Precondition Failed and returns true when it does not
+// match. Requests without If-Match always pass.
+func preconditionFailed(c *gin.Context, quote models.Quote) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" || etagListed(header, quoteETag(quote), false) {
+		return false
+	}
+	c.Header("ETag", quoteETag(quote))
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Quote has been modified", "etag": quoteETag(quote)})
+	return true
+}
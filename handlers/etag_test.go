@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestEtagListed(t *testing.T) {
+	assert.True(t, etagListed(`"a", "b"`, `"b"`, false))
+	assert.True(t, etagListed(`*`, `"b"`, false))
+	assert.False(t, etagListed(`W/"b"`, `"b"`, false))
+	assert.True(t, etagListed(`W/"b"`, `"b"`, true))
+}
+
+func TestQuoteConditionalRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	user := models.User{Username: "editor", Password: "hashed", Role: models.RoleModerator}
+	db.Create(&user)
+	quote := models.Quote{Content: "Original", Author: "someone"}
+	db.Create(&quote)
+
+	r := gin.Default()
+	r.GET("/quotes", GetQuotes)
+	r.GET("/quotes/:id", GetQuote)
+	authed := r.Group("/", func(c *gin.Context) { c.Set("user_id", user.ID) })
+	authed.PUT("/quotes/:id", UpdateQuote)
+	authed.DELETE("/quotes/:id", DeleteQuote)
+
+	send := func(method, path string, headers map[string]string, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	path := fmt.Sprintf("/quotes/%d", quote.ID)
+
+	w := send("GET", path, nil, nil)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// Conditional GETs
+	w = send("GET", path, map[string]string{"If-None-Match": etag}, nil)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = send("GET", "/quotes", nil, nil)
+	listETag := w.Header().Get("ETag")
+	assert.Equal(t, http.StatusNotModified, send("GET", "/quotes", map[string]string{"If-None-Match": listETag}, nil).Code)
+
+	// Two editors start from the same version, the second one loses
+	update := map[string]interface{}{"content": "First edit", "author": "someone"}
+	w = send("PUT", path, map[string]string{"If-Match": etag}, update)
+	assert.Equal(t, http.StatusOK, w.Code)
+	newETag := w.Header().Get("ETag")
+	assert.NotEqual(t, etag, newETag)
+
+	update["content"] = "Second edit"
+	w = send("PUT", path, map[string]string{"If-Match": etag}, update)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	db.First(&quote, quote.ID)
+	assert.Equal(t, "First edit", quote.Content)
+	assert.EqualValues(t, 2, quote.Version)
+
+	// Clients cannot set the version themselves
+	update["version"] = 99
+	w = send("PUT", path, map[string]string{"If-Match": newETag}, update)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&quote, quote.ID)
+	assert.EqualValues(t, 3, quote.Version)
+
+	// Stale list and quote ETags no longer match
+	assert.Equal(t, http.StatusOK, send("GET", path, map[string]string{"If-None-Match": etag}, nil).Code)
+	assert.Equal(t, http.StatusOK, send("GET", "/quotes", map[string]string{"If-None-Match": listETag}, nil).Code)
+
+	assert.Equal(t, http.StatusPreconditionFailed, send("DELETE", path, map[string]string{"If-Match": newETag}, nil).Code)
+	current := send("GET", path, nil, nil).Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, send("DELETE", path, map[string]string{"If-Match": current}, nil).Code)
+}
+
+func TestDeleteQuoteChangedMeanwhile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	user := models.User{Username: "editor", Password: "hashed", Role: models.RoleModerator}
+	db.Create(&user)
+	quotes := []models.Quote{{Content: "Edited meanwhile", Author: "someone"}, {Content: "Voted meanwhile", Author: "someone"}}
+	db.Create(&quotes)
+
+	r := gin.Default()
+	r.DELETE("/quotes/:id", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		DeleteQuote(c)
+	})
+
+	// Change the quote after the handler checked it, right before it is deleted
+	var change func(tx *gorm.DB)
+	db.Callback().Delete().Before("gorm:delete").Register("test:change_quote", func(tx *gorm.DB) {
+		if change != nil {
+			change(tx.Session(&gorm.Session{NewDB: true}))
+		}
+	})
+	changes := []func(tx *gorm.DB){
+		func(tx *gorm.DB) {
+			tx.Model(&models.Quote{}).Where("id = ?", quotes[0].ID).Updates(map[string]interface{}{"content": "Changed", "version": bumpVersion})
+		},
+		func(tx *gorm.DB) {
+			tx.Create(&models.Vote{QuoteID: quotes[1].ID})
+		},
+	}
+	for i, quote := range quotes {
+		change = changes[i]
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/quotes/%d", quote.ID), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, quote.Content)
+		assert.NoError(t, db.First(&models.Quote{}, quote.ID).Error, "the quote is kept")
+	}
+}
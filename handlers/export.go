@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"archive/tar"
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"Qoute-backend/config"
+	"Qoute-backend/formats"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportPageSize is how many records an export loads from the database at
+// once. Each page is flushed to the client once written.
+const exportPageSize = 500
+
+// exportedQuote is a quote as exported, in the format of GetQuotes
+type exportedQuote struct {
+	QuoteResponse
+}
+
+func (q exportedQuote) CSVFields() []string {
+	source := q.Source
+	return []string{
+		formatUint(q.ID), q.Content, q.Author, q.Language, formatUintPtr(q.TranslationOf),
+		source.WorkTitle, formatIntPtr(source.Year), source.Page, source.URL, source.License, source.Verification,
+		q.Status, strconv.FormatBool(q.Hidden), formatUintPtr(q.UserID),
+		strconv.FormatFloat(q.Rating, 'f', -1, 64), strconv.Itoa(q.Battles), strconv.Itoa(q.VoteCount), strconv.Itoa(q.CommentCount),
+		formatTimePtr(q.PublishAt), formatTimePtr(q.PublishedAt), formatTime(q.CreatedAt), formatTime(q.UpdatedAt),
+	}
+}
+
+// exportedVote is a vote as exported
+type exportedVote struct {
+	ID        uint      `json:"id"`
+	QuoteID   uint      `json:"quote_id"`
+	UserID    *uint     `json:"user_id"` // Only set when votes are public
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// voteColumns are the CSV columns of exported votes
+var voteColumns = []string{"id", "quote_id", "user_id", "created_at"}
+
+func (v exportedVote) CSVFields() []string {
+	return []string{formatUint(v.ID), formatUint(v.QuoteID), formatUintPtr(v.UserID), formatTime(v.CreatedAt)}
+}
+
+func formatUint(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+func formatUintPtr(value *uint) string {
+	if value == nil {
+		return ""
+	}
+	return formatUint(*value)
+}
+
+func formatIntPtr(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func formatTime(value time.Time) string {
+	return value.Format(time.RFC3339)
+}
+
+func formatTimePtr(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return formatTime(*value)
+}
+
+// exportFormat reads the format query param, JSON by default
+func exportFormat(c *gin.Context, supported []string) (string, bool) {
+	format := c.DefaultQuery("format", formats.JSON)
+	if !slices.Contains(supported, format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format", "formats": supported})
+		return "", false
+	}
+	return format, true
+}
+
+// exportHeaders names the download of an export
+func exportHeaders(c *gin.Context, contentType, name, extension string) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, name, time.Now().Format("20060102"), extension))
+}
+
+// exportPage loads the next page of an export, at most exportPageSize
+// records. An empty page ends the export.
+type exportPage[T formats.Record] func() ([]T, error)
+
+// streamExport writes every record of an export as a download named after
+// what is exported. Records are loaded a page at a time, and each result set
+// is closed before the page is written, so a slow client never keeps the
+// database from other requests. Once the download started errors can no
+// longer be reported, so they end the response early.
+func streamExport[T formats.Record](c *gin.Context, next exportPage[T], format, name string, header []string) {
+	page, err := next()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exportHeaders(c, formats.ContentType(format), name, format)
+	c.Status(http.StatusOK)
+	writer, err := formats.NewWriter(format, c.Writer, header)
+	for err == nil && len(page) > 0 {
+		if err = writePage(page, writer, c.Writer); err == nil {
+			page, err = next()
+		}
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Printf("Export of %s failed: %v", name, err)
+		c.Abort()
+	}
+}
+
+func writePage[T formats.Record](page []T, writer *formats.Writer, w http.Flusher) error {
+	for _, record := range page {
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+// quotePages pages through the quotes a query selects, in its order. The
+// IDs are listed first, since the sort columns can't be used as a keyset,
+// and each page is then loaded by ID.
+func quotePages(query func() *gorm.DB, order string) exportPage[exportedQuote] {
+	var ids []uint
+	listed := false
+	return func() ([]exportedQuote, error) {
+		if !listed {
+			if err := query().Order(order).Pluck("quotes.id", &ids).Error; err != nil {
+				return nil, err
+			}
+			listed = true
+		}
+		pageIDs := ids[:min(len(ids), exportPageSize)]
+		ids = ids[len(pageIDs):]
+		if len(pageIDs) == 0 {
+			return nil, nil
+		}
+
+		var loaded []exportedQuote
+		err := query().Select("quotes.*, "+voteCountSQL+" AS vote_count, "+commentCountSQL+" AS comment_count").
+			Where("quotes.id IN ?", pageIDs).Find(&loaded).Error
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[uint]exportedQuote, len(loaded))
+		for _, quote := range loaded {
+			byID[quote.ID] = quote
+		}
+		// Quotes removed since they were listed are left out
+		page := make([]exportedQuote, 0, len(loaded))
+		for _, id := range pageIDs {
+			if quote, ok := byID[id]; ok {
+				page = append(page, quote)
+			}
+		}
+		return page, nil
+	}
+}
+
+// exportFilters reads the GetQuotes filters, responding with 400 when they
+// are invalid
+func exportFilters(c *gin.Context) (quoteViewer, func(*gorm.DB) *gorm.DB, bool) {
+	viewer, err := loadQuoteViewer(c, config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return viewer, nil, false
+	}
+	filters, err := quoteFilters(c, viewer)
+	if err != nil {
+		respondWithFilterError(c, err)
+		return viewer, nil, false
+	}
+	return viewer, filters, true
+}
+
+// ExportQuotes downloads the quotes the viewer can see as CSV, JSON or
+// NDJSON, with the filters and sorting of GetQuotes. Quotes are loaded from
+// the database page by page as they are written.
+func ExportQuotes(c *gin.Context) {
+	format, ok := exportFormat(c, append(slices.Clone(formats.WriterNames), formats.Fortune))
+	if !ok {
+		return
+	}
+	viewer, filters, ok := exportFilters(c)
+	if !ok {
+		return
+	}
+	order, err := quoteOrder(c)
+	if err != nil {
+		respondWithFilterError(c, err)
+		return
+	}
+
+	pages := quotePages(func() *gorm.DB {
+		return config.DB.Model(&models.Quote{}).Scopes(viewer.visible, filters)
+	}, order)
+	if format == formats.Fortune {
+		exportFortunes(c, pages)
+		return
+	}
+	streamExport(c, pages, format, "quotes", formats.QuoteColumns)
+}
+
+// exportFortunes downloads quotes as a tar archive holding a fortune file
+// and its strfile index, ready for fortune. The archive needs the size of
+// the fortune file up front, so it is first written to a temporary file
+// while the index is built.
+func exportFortunes(c *gin.Context, pages exportPage[exportedQuote]) {
+	file, err := os.CreateTemp("", "quotes-*.fortune")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	fortunes, err := writeFortunes(pages, file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	exportHeaders(c, "application/x-tar", "quotes", "tar")
+	c.Status(http.StatusOK)
+	archive := tar.NewWriter(c.Writer)
+	now := time.Now()
+	err = archive.WriteHeader(&tar.Header{Name: "quotes", Mode: 0o644, Size: fortunes.Len(), ModTime: now})
+	if err == nil {
+		_, err = io.Copy(archive, file)
+	}
+	if err == nil {
+		err = archive.WriteHeader(&tar.Header{Name: "quotes.dat", Mode: 0o644, Size: fortunes.IndexLen(), ModTime: now})
+	}
+	if err == nil {
+		err = fortunes.WriteIndex(archive)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		log.Printf("Export of fortunes failed: %v", err)
+		c.Abort()
+	}
+}
+
+// writeFortunes writes every quote of an export as a fortune
+func writeFortunes(next exportPage[exportedQuote], w io.Writer) (*formats.FortuneWriter, error) {
+	buffered := bufio.NewWriter(w)
+	fortunes := formats.NewFortuneWriter(buffered)
+	for {
+		page, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, quote := range page {
+			if err := fortunes.Write(quote.Content, quote.Author, quote.Source.WorkTitle); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return fortunes, buffered.Flush()
+}
+
+// ExportVotes downloads votes as CSV, JSON or NDJSON, oldest first. The
+// GetQuotes filters pick the quotes whose votes are exported. Voters are
+// left out unless the vote privacy mode is public.
+func ExportVotes(c *gin.Context) {
+	format, ok := exportFormat(c, formats.WriterNames)
+	if !ok {
+		return
+	}
+	viewer, filters, ok := exportFilters(c)
+	if !ok {
+		return
+	}
+
+	columns := []string{"id", "quote_id", "created_at"}
+	if config.VotePrivacy() == config.VotePrivacyPublic {
+		columns = append(columns, "user_id")
+	}
+
+	// Votes are paged by ID, after the last one of the previous page
+	var lastID uint
+	streamExport(c, func() ([]exportedVote, error) {
+		var page []exportedVote
+		quotes := config.DB.Model(&models.Quote{}).Select("quotes.id").Scopes(viewer.visible, filters)
+		err := config.DB.Model(&models.Vote{}).Select(columns).
+			Where("quote_id IN (?) AND id > ?", quotes, lastID).Order("id asc").Limit(exportPageSize).Find(&page).Error
+		if len(page) > 0 {
+			lastID = page[len(page)-1].ID
+		}
+		return page, err
+	}, format, "votes", voteColumns)
+}
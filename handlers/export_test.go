@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/formats"
+	"Qoute-backend/models"
+	"archive/tar"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupExportTest() (*gin.Engine, []models.User, []models.Quote) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	users := []models.User{{Username: "admin", Password: "x", Role: models.RoleAdmin}, {Username: "reader", Password: "x"}}
+	db.Create(&users)
+	year := 2005
+	quotes := []models.Quote{
+		{Content: "Stay hungry, stay foolish.", Author: "Steve Jobs", Language: "en", Source: models.Citation{WorkTitle: "Stanford address", Year: &year}},
+		{Content: "Be the change that you wish to see in the world.", Author: "Mahatma Gandhi", Language: "en"},
+		{Content: "Waiting for review.", Author: "Someone", Language: "en", Status: models.QuoteStatusPending},
+	}
+	db.Create(&quotes)
+	db.Create(&models.Vote{QuoteID: quotes[0].ID, UserID: &users[0].ID})
+	db.Create(&models.Vote{QuoteID: quotes[0].ID, UserID: &users[1].ID})
+	db.Create(&models.Vote{QuoteID: quotes[1].ID})
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		if id, err := strconv.Atoi(c.Query("as")); err == nil {
+			c.Set("user_id", uint(id))
+		}
+		c.Next()
+	})
+	r.GET("/export/quotes", ExportQuotes)
+	r.GET("/export/votes", ExportVotes)
+	return r, users, quotes
+}
+
+func export(r *gin.Engine, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportQuotes(t *testing.T) {
+	r, users, quotes := setupExportTest()
+
+	// JSON, in the format of GetQuotes
+	w := export(r, "/export/quotes?sortBy=votes&order=desc")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename="quotes-\d{8}\.json"$`, w.Header().Get("Content-Disposition"))
+	var exported []QuoteResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &exported))
+	require.Len(t, exported, 2, "pending quotes are left out")
+	assert.Equal(t, quotes[0].ID, exported[0].ID)
+	assert.Equal(t, 2, exported[0].VoteCount)
+	assert.Equal(t, "Stanford address", exported[0].Source.WorkTitle)
+	assert.Empty(t, exported[0].Votes, "votes are exported separately")
+
+	// CSV with the filters of GetQuotes
+	w = export(r, "/export/quotes?format=csv&author=Mahatma%20Gandhi")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, formats.QuoteColumns, records[0])
+	assert.Equal(t, strconv.Itoa(int(quotes[1].ID)), records[1][0])
+	assert.Equal(t, "Be the change that you wish to see in the world.", records[1][1])
+	assert.Equal(t, "1", records[1][16])
+
+	// NDJSON, where admins also see pending quotes
+	w = export(r, fmt.Sprintf("/export/quotes?format=ndjson&as=%d", users[0].ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	lines := 0
+	for scanner := bufio.NewScanner(w.Body); scanner.Scan(); lines++ {
+		var quote QuoteResponse
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &quote))
+	}
+	assert.Equal(t, 3, lines)
+
+	// Nothing to export is still a valid file
+	w = export(r, "/export/quotes?author=nobody")
+	assert.Equal(t, "[]\n", w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, export(r, "/export/quotes?format=xml").Code)
+	assert.Equal(t, http.StatusBadRequest, export(r, "/export/quotes?filter=votes>>1").Code)
+}
+
+func TestExportedQuotesCanBeImported(t *testing.T) {
+	r, users, _ := setupExportTest()
+	w := export(r, "/export/quotes?format=csv")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	config.DB.Exec("DELETE FROM quotes")
+	result, response := importQuotes(setupImportRouter(), fmt.Sprintf("as=%d", users[0].ID), "text/csv", w.Body.String())
+	require.Equal(t, http.StatusCreated, response.Code)
+	assert.Equal(t, 2, result.Inserted)
+
+	var quote models.Quote
+	config.DB.Where("author = ?", "Steve Jobs").First(&quote)
+	assert.Equal(t, "Stanford address", quote.Source.WorkTitle)
+	assert.Equal(t, 2005, *quote.Source.Year)
+}
+
+func TestExportVotes(t *testing.T) {
+	r, users, quotes := setupExportTest()
+
+	w := export(r, "/export/votes?format=csv")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Regexp(t, `filename="votes-\d{8}\.csv"`, w.Header().Get("Content-Disposition"))
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, []string{"id", "quote_id", "user_id", "created_at"}, records[0])
+	assert.Equal(t, strconv.Itoa(int(users[0].ID)), records[1][2])
+	assert.Equal(t, "", records[3][2], "anonymous votes have no user")
+
+	// Only votes of quotes matching the filters
+	w = export(r, fmt.Sprintf("/export/votes?ids=%d", quotes[1].ID))
+	var votes []exportedVote
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &votes))
+	require.Len(t, votes, 1)
+	assert.Equal(t, quotes[1].ID, votes[0].QuoteID)
+	assert.Nil(t, votes[0].UserID)
+
+	// Voters are never returned unless votes are public
+	defer os.Unsetenv("VOTE_PRIVACY")
+	for _, mode := range []string{config.VotePrivacyCounts, config.VotePrivacyAnonymous} {
+		os.Setenv("VOTE_PRIVACY", mode)
+		w = export(r, "/export/votes")
+		votes = nil
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &votes))
+		require.Len(t, votes, 3, mode)
+		for _, vote := range votes {
+			assert.Nil(t, vote.UserID, mode)
+		}
+		assert.NotContains(t, w.Body.String(), `"user_id":`+strconv.Itoa(int(users[0].ID)), mode)
+
+		w = export(r, "/export/votes?format=csv")
+		records, err = csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		for _, record := range records[1:] {
+			assert.Equal(t, "", record[2], mode)
+		}
+	}
+}
+
+// pausingRecorder blocks the first flush of a response until it is resumed
+type pausingRecorder struct {
+	*httptest.ResponseRecorder
+	paused, resume chan struct{}
+}
+
+func (w *pausingRecorder) Flush() {
+	if w.paused != nil {
+		close(w.paused)
+		w.paused = nil
+		<-w.resume
+	}
+	w.ResponseRecorder.Flush()
+}
+
+func TestExportDoesNotBlockWrites(t *testing.T) {
+	r, _, quotes := setupExportTest()
+	votes := make([]models.Vote, exportPageSize)
+	for i := range votes {
+		votes[i] = models.Vote{QuoteID: quotes[1].ID}
+	}
+	require.NoError(t, config.DB.CreateInBatches(&votes, 100).Error)
+
+	for _, path := range []string{"/export/votes?format=ndjson", "/export/quotes?format=ndjson"} {
+		w := &pausingRecorder{httptest.NewRecorder(), make(chan struct{}), make(chan struct{})}
+		paused := w.paused
+		req, _ := http.NewRequest("GET", path, nil)
+		done := make(chan struct{})
+		go func() {
+			r.ServeHTTP(w, req)
+			close(done)
+		}()
+		<-paused
+
+		// The database takes writes while the client reads the first page
+		written := make(chan error)
+		go func() {
+			written <- config.DB.Create(&models.Vote{QuoteID: quotes[0].ID}).Error
+		}()
+		select {
+		case err := <-written:
+			assert.NoError(t, err, path)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: write blocked by the export", path)
+		}
+		close(w.resume)
+		<-done
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+
+	w := export(r, "/export/votes?format=ndjson")
+	assert.Equal(t, exportPageSize+5, strings.Count(w.Body.String(), "\n"))
+}
+
+func TestExportFortunes(t *testing.T) {
+	r, users, _ := setupExportTest()
+
+	w := export(r, "/export/quotes?format=fortune&sortBy=author&order=asc")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-tar", w.Header().Get("Content-Type"))
+	assert.Regexp(t, `filename="quotes-\d{8}\.tar"`, w.Header().Get("Content-Disposition"))
+
+	files := map[string][]byte{}
+	var names []string
+	archive := tar.NewReader(w.Body)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		files[header.Name], _ = io.ReadAll(archive)
+		names = append(names, header.Name)
+	}
+	assert.Equal(t, []string{"quotes", "quotes.dat"}, names)
+	assert.Equal(t, "Be the change that you wish to see in the world.\n\t\t-- Mahatma Gandhi\n%\n"+
+		"Stay hungry, stay foolish.\n\t\t-- Steve Jobs, \"Stanford address\"\n%\n", string(files["quotes"]))
+	assert.Len(t, files["quotes.dat"], 24+3*4)
+
+	// Fortune files can be imported again
+	config.DB.Exec("DELETE FROM quotes")
+	result, response := importQuotes(setupImportRouter(), fmt.Sprintf("as=%d&format=fortune", users[0].ID), "text/plain", string(files["quotes"]))
+	require.Equal(t, http.StatusCreated, response.Code)
+	assert.Equal(t, 2, result.Inserted)
+	var quote models.Quote
+	config.DB.Where("author = ?", "Steve Jobs").First(&quote)
+	assert.Equal(t, "Stanford address", quote.Source.WorkTitle)
+
+	// Votes cannot be fortunes
+	assert.Equal(t, http.StatusBadRequest, export(r, "/export/votes?format=fortune").Code)
+}
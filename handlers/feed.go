@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Feed limits
+const (
+	defaultFeedPageSize = 20
+	maxFeedPageSize     = 100
+	maxAuthorLength     = 200
+	maxFeedSeeds        = 50 // Liked quotes used to find similar wording
+)
+
+// Weights of the signals ranking the feed
+const (
+	followWeight      = 1.0
+	likedAuthorWeight = 0.5
+	similarWeight     = 0.8
+	tasteWeight       = 0.3
+	popularityWeight  = 0.1
+	freshnessWeight   = 0.1
+	feedLanguageBonus = 0.02
+)
+
+type FeedHandler struct {
+	db      *gorm.DB
+	related *RelatedHandler  // Shares its text index
+	now     func() time.Time // Time the first page of a feed is ranked at
+}
+
+func NewFeedHandler(db *gorm.DB, related *RelatedHandler) *FeedHandler {
+	return &FeedHandler{db: db, related: related, now: time.Now}
+}
+
+// FollowAuthorInput is the body of a follow request
+type FollowAuthorInput struct {
+	Author string `json:"author" binding:"required"`
+}
+
+// FeedItem is a recommended quote with why it was recommended
+type FeedItem struct {
+	QuoteResponse
+	Score       float64  `json:"score"`
+	Explanation string   `json:"explanation"` // The main reason
+	Reasons     []string `json:"reasons"`
+}
+
+// FeedResponse is a page of the feed
+type FeedResponse struct {
+	Items      []FeedItem `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// feedSignals is the evidence that a user will like a quote
+type feedSignals struct {
+	followed    bool
+	likedAuthor int     // Liked quotes by the same author
+	similar     float64 // Best text similarity to a liked quote
+	similarTo   string  // Author of that liked quote
+	tasteLikes  int     // Users sharing a like with the user who liked the quote
+	sameLang    bool
+	rating      float64
+	createdAt   time.Time
+}
+
+func (s feedSignals) score(now time.Time) float64 {
+	score := 0.0
+	if s.followed {
+		score += followWeight
+	}
+	if s.likedAuthor > 0 {
+		score += likedAuthorWeight * math.Min(float64(s.likedAuthor), 3) / 3
+	}
+	score += similarWeight * s.similar
+	if s.tasteLikes > 0 {
+		score += tasteWeight * math.Min(math.Log2(1+float64(s.tasteLikes))/3, 1)
+	}
+	score += popularityWeight * math.Max(0, math.Min((s.rating-1500)/400, 1))
+	score += freshnessWeight * math.Exp(-now.Sub(s.createdAt).Hours()/24/30)
+	if s.sameLang {
+		score += feedLanguageBonus
+	}
+	return score
+}
+
+// reasons explains the score in words, strongest first
+func (s feedSignals) reasons(author string, now time.Time) []string {
+	var reasons []string
+	if s.followed {
+		reasons = append(reasons, "From "+author+", whom you follow")
+	}
+	if s.similar >= 0.2 {
+		reasons = append(reasons, "Similar to a quote by "+s.similarTo+" you liked")
+	}
+	if s.likedAuthor > 0 && !s.followed {
+		reasons = append(reasons, "By "+author+", whose quotes you liked")
+	}
+	if s.tasteLikes > 0 {
+		reasons = append(reasons, "Liked by people with similar taste")
+	}
+	if len(reasons) == 0 {
+		switch {
+		case s.rating >= 1550:
+			reasons = append(reasons, "Popular in quote battles")
+		case now.Sub(s.createdAt) < 7*24*time.Hour:
+			reasons = append(reasons, "Recently added")
+		default:
+			reasons = append(reasons, "Something new to discover")
+		}
+	}
+	return reasons
+}
+
+// feedCursor is the position after the last item of a page. Freshness
+// decays over time, so it also keeps the time the feed was ranked at, and
+// later pages are ranked at that time too.
+type feedCursor struct {
+	score float64
+	id    uint
+	now   time.Time
+}
+
+func (c feedCursor) String() string {
+	raw := strconv.FormatFloat(c.score, 'g', -1, 64) + ":" + strconv.FormatUint(uint64(c.id), 10) +
+		":" + strconv.FormatInt(c.now.UnixNano(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseFeedCursor(s string) (*feedCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed cursor")
+	}
+	score, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	now, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &feedCursor{score, uint(id), time.Unix(0, now)}, nil
+}
+
+// after reports whether an item ranks after the cursor
+func (c *feedCursor) after(score float64, id uint) bool {
+	return c == nil || score < c.score || (score == c.score && id > c.id)
+}
+
+// GetFeed ranks public quotes for the current user by the authors they
+// follow and the quotes they liked, leaving out quotes they already liked
+// and their own. Pages continue from the cursor of the previous page, ranked
+// as they were when the first page was.
+func (h *FeedHandler) GetFeed(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultFeedPageSize)))
+	if err != nil || limit < 1 || limit > maxFeedPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+	cursor, err := parseFeedCursor(c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
+	viewer, err := loadQuoteViewer(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// What the user likes, including votes stored without their user ID
+	likes, err := quoteLikes(h.db, "user_id", []uint{userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	liked := map[uint]bool{}
+	for _, like := range likes {
+		liked[like.QuoteID] = true
+	}
+	for id := range viewer.votedQuoteIDs {
+		liked[id] = true
+	}
+	likedIDs := make([]uint, 0, len(liked))
+	for id := range liked {
+		likedIDs = append(likedIDs, id)
+	}
+	sort.Slice(likedIDs, func(i, j int) bool { return likedIDs[i] > likedIDs[j] })
+
+	var likedQuotes []models.Quote
+	if err := h.db.Select("id", "author", "language").Where("id IN ?", likedIDs).Find(&likedQuotes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	likedAuthors := map[string]int{}
+	likedLanguages := map[string]bool{}
+	for _, quote := range likedQuotes {
+		likedAuthors[models.AuthorKey(quote.Author)]++
+		likedLanguages[primaryLanguage(quote.Language)] = true
+	}
+
+	var follows []models.AuthorFollow
+	if err := h.db.Where("user_id = ?", userID).Find(&follows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	followed := map[string]bool{}
+	for _, follow := range follows {
+		followed[follow.AuthorKey] = true
+	}
+
+	taste, err := h.tasteLikes(userID, likedIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Similar wording to the most recently liked quotes
+	similar := map[uint]float64{}
+	similarTo := map[uint]string{}
+	if len(likedQuotes) > 0 {
+		if err := h.related.refreshIndex(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index quotes"})
+			return
+		}
+		likedAuthor := map[uint]string{}
+		for _, quote := range likedQuotes {
+			likedAuthor[quote.ID] = quote.Author
+		}
+		for i, id := range likedIDs {
+			if i == maxFeedSeeds {
+				break
+			}
+			for other, similarity := range h.related.index.Similar(id) {
+				if similarity.Text > similar[other] {
+					similar[other] = similarity.Text
+					similarTo[other] = likedAuthor[id]
+				}
+			}
+		}
+	}
+
+	// Rank every public quote the user has not liked or submitted
+	var candidates []models.Quote
+	query := h.db.Model(&models.Quote{}).Scopes(publiclyVisible).
+		Select("id", "author", "language", "rating", "created_at").
+		Where("(quotes.user_id IS NULL OR quotes.user_id <> ?)", userID)
+	if len(likedIDs) > 0 {
+		query = query.Where("quotes.id NOT IN ?", likedIDs)
+	}
+	if err := query.Find(&candidates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := h.now()
+	if cursor != nil {
+		now = cursor.now
+	}
+	type ranked struct {
+		quote   models.Quote
+		signals feedSignals
+		score   float64
+	}
+	var ranking []ranked
+	for _, quote := range candidates {
+		signals := feedSignals{
+			followed:    followed[models.AuthorKey(quote.Author)],
+			likedAuthor: likedAuthors[models.AuthorKey(quote.Author)],
+			similar:     similar[quote.ID],
+			similarTo:   similarTo[quote.ID],
+			tasteLikes:  taste[quote.ID],
+			sameLang:    likedLanguages[primaryLanguage(quote.Language)],
+			rating:      quote.Rating,
+			createdAt:   quote.CreatedAt,
+		}
+		score := math.Round(signals.score(now)*1e6) / 1e6
+		if cursor.after(score, quote.ID) {
+			ranking = append(ranking, ranked{quote, signals, score})
+		}
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].score != ranking[j].score {
+			return ranking[i].score > ranking[j].score
+		}
+		return ranking[i].quote.ID < ranking[j].quote.ID
+	})
+
+	response := FeedResponse{Items: []FeedItem{}}
+	if len(ranking) > limit {
+		last := ranking[limit-1]
+		response.NextCursor = feedCursor{last.score, last.quote.ID, now}.String()
+		ranking = ranking[:limit]
+	}
+
+	ids := make([]uint, len(ranking))
+	for i, r := range ranking {
+		ids[i] = r.quote.ID
+	}
+	var quotes []models.Quote
+	if err := h.db.Scopes(withResponseRelations).Where("id IN ?", ids).Find(&quotes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byID := make(map[uint]models.Quote, len(quotes))
+	for _, quote := range quotes {
+		byID[quote.ID] = quote
+	}
+	for _, r := range ranking {
+		quote, ok := byID[r.quote.ID]
+		if !ok {
+			continue
+		}
+		reasons := r.signals.reasons(quote.Author, now)
+		response.Items = append(response.Items, FeedItem{
+			QuoteResponse: viewer.response(quote),
+			Score:         r.score,
+			Explanation:   reasons[0],
+			Reasons:       reasons,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// tasteLikes counts, for every quote, the other users who liked it and
+// share a liked quote with the user
+func (h *FeedHandler) tasteLikes(userID uint, likedIDs []uint) (map[uint]int, error) {
+	likers, err := quoteLikes(h.db, "quote_id", likedIDs)
+	if err != nil {
+		return nil, err
+	}
+	similarUsers := map[uint]bool{}
+	for _, like := range likers {
+		if like.UserID != userID {
+			similarUsers[like.UserID] = true
+		}
+	}
+	userIDs := make([]uint, 0, len(similarUsers))
+	for id := range similarUsers {
+		userIDs = append(userIDs, id)
+	}
+
+	likes, err := quoteLikes(h.db, "user_id", userIDs)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[uint]int{}
+	for _, like := range likes {
+		counts[like.QuoteID]++ // The union has one row per user and quote
+	}
+	return counts, nil
+}
+
+// GetFollows lists the authors the current user follows
+func (h *FeedHandler) GetFollows(c *gin.Context) {
+	var follows []models.AuthorFollow
+	if err := h.db.Where("user_id = ?", c.GetUint("user_id")).Order("author asc").Find(&follows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, follows)
+}
+
+// FollowAuthor makes the current user follow an author
+func (h *FeedHandler) FollowAuthor(c *gin.Context) {
+	var input FollowAuthorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	author := strings.Join(strings.Fields(input.Author), " ")
+	if author == "" || len([]rune(author)) > maxAuthorLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "author must be between 1 and 200 characters"})
+		return
+	}
+
+	// The unique author key makes a concurrent duplicate a no-op
+	follow := models.AuthorFollow{UserID: c.GetUint("user_id"), Author: author}
+	result := h.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow author"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already follow this author"})
+		return
+	}
+	c.JSON(http.StatusCreated, follow)
+}
+
+// UnfollowAuthor stops the current user following an author
+func (h *FeedHandler) UnfollowAuthor(c *gin.Context) {
+	author := models.AuthorKey(c.Param("author"))
+	result := h.db.Where("user_id = ? AND author_key = ?", c.GetUint("user_id"), author).Delete(&models.AuthorFollow{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow author"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You do not follow this author"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Author unfollowed"})
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupFeedRouter(h *FeedHandler) *gin.Engine {
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Query("as"))
+		c.Set("user_id", uint(id))
+		c.Next()
+	})
+	r.GET("/me/feed", h.GetFeed)
+	r.GET("/me/follows", h.GetFollows)
+	r.POST("/me/follows", h.FollowAuthor)
+	r.DELETE("/me/follows/:author", h.UnfollowAuthor)
+	return r
+}
+
+func TestAuthorFollows(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	users := []models.User{{Username: "reader", Password: "x"}, {Username: "other", Password: "x"}}
+	db.Create(&users)
+	r := setupFeedRouter(NewFeedHandler(db, NewRelatedHandler(db)))
+
+	follow := func(as uint, author string) int {
+		body, _ := json.Marshal(gin.H{"author": author})
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/me/follows?as=%d", as), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusCreated, follow(users[0].ID, "  Marcus   Aurelius "))
+	assert.Equal(t, http.StatusConflict, follow(users[0].ID, "marcus aurelius"))
+	assert.Equal(t, http.StatusCreated, follow(users[1].ID, "Marcus Aurelius"))
+	assert.Equal(t, http.StatusCreated, follow(users[0].ID, "Epictetus"))
+	assert.Equal(t, http.StatusBadRequest, follow(users[0].ID, "   "))
+	// Case is folded beyond ASCII
+	assert.Equal(t, http.StatusCreated, follow(users[1].ID, "Émile Zola"))
+	assert.Equal(t, http.StatusConflict, follow(users[1].ID, "émile ZOLA"))
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/me/follows?as=%d", users[0].ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var follows []models.AuthorFollow
+	json.Unmarshal(w.Body.Bytes(), &follows)
+	require.Len(t, follows, 2)
+	assert.Equal(t, "Epictetus", follows[0].Author)
+	assert.Equal(t, "Marcus Aurelius", follows[1].Author)
+
+	unfollow := func(author string) int {
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/me/follows/%s?as=%d", url.PathEscape(author), users[0].ID), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, unfollow("MARCUS AURELIUS"))
+	assert.Equal(t, http.StatusNotFound, unfollow("Marcus Aurelius"))
+
+	var count int64
+	db.Model(&models.AuthorFollow{}).Count(&count)
+	assert.Equal(t, int64(3), count, "the other user's follows are kept")
+
+	// The database refuses duplicates that slip past the handler
+	assert.Error(t, db.Create(&models.AuthorFollow{UserID: users[1].ID, Author: "ÉMILE zola"}).Error)
+}
+
+func TestFeed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	users := []models.User{{Username: "reader", Password: "x"}, {Username: "kindred", Password: "x"}}
+	db.Create(&users)
+	quotes := []models.Quote{
+		{Content: "The only true wisdom is in knowing you know nothing.", Author: "Socrates", Language: "en"},
+		{Content: "Knowing yourself is the beginning of all wisdom.", Author: "Aristotle", Language: "en"},
+		{Content: "You have power over your mind, not outside events.", Author: "Marcus Aurelius", Language: "en"},
+		{Content: "Stay hungry, stay foolish.", Author: "Steve Jobs", Language: "en"},
+		{Content: "An unexamined life is not worth living.", Author: "Socrates", Language: "en"},
+		{Content: "Be the change that you wish to see in the world.", Author: "Mahatma Gandhi", Language: "en"},
+		{Content: "My own quote, submitted by me.", Author: "Reader", Language: "en", UserID: &users[0].ID},
+		{Content: "Waiting for review.", Author: "Marcus Aurelius", Language: "en", Status: models.QuoteStatusPending},
+	}
+	db.Create(&quotes)
+
+	// The reader liked the first quote and follows Marcus Aurelius. Someone
+	// else who liked it also picked the fourth in a battle.
+	db.Create(&models.Vote{QuoteID: quotes[0].ID, UserID: &users[0].ID})
+	db.Create(&models.AuthorFollow{UserID: users[0].ID, Author: "marcus aurelius"})
+	db.Create(&models.Battle{UserID: users[1].ID, QuoteAID: quotes[0].ID, QuoteBID: quotes[5].ID, WinnerID: &quotes[0].ID})
+	db.Create(&models.Battle{UserID: users[1].ID, QuoteAID: quotes[3].ID, QuoteBID: quotes[5].ID, WinnerID: &quotes[3].ID})
+
+	r := setupFeedRouter(NewFeedHandler(db, NewRelatedHandler(db)))
+	get := func(query string) (FeedResponse, int) {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/me/feed?as=%d&%s", users[0].ID, query), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var response FeedResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response, w.Code
+	}
+
+	feed, code := get("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, feed.NextCursor)
+	ids := make([]uint, len(feed.Items))
+	for i, item := range feed.Items {
+		ids[i] = item.ID
+		assert.Equal(t, item.Reasons[0], item.Explanation)
+	}
+	// Liked, own and pending quotes are left out
+	assert.Equal(t, []uint{quotes[2].ID, quotes[1].ID, quotes[4].ID, quotes[3].ID, quotes[5].ID}, ids)
+	assert.Equal(t, "From Marcus Aurelius, whom you follow", feed.Items[0].Explanation)
+	assert.Equal(t, "Similar to a quote by Socrates you liked", feed.Items[1].Explanation)
+	assert.Equal(t, "By Socrates, whose quotes you liked", feed.Items[2].Explanation)
+	assert.Equal(t, "Liked by people with similar taste", feed.Items[3].Explanation)
+	assert.Equal(t, "Recently added", feed.Items[4].Explanation)
+
+	// Pages follow on from the cursor without repeats
+	var paged []uint
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		feed, code = get("limit=2&cursor=" + cursor)
+		require.Equal(t, http.StatusOK, code)
+		for _, item := range feed.Items {
+			paged = append(paged, item.ID)
+		}
+		cursor = feed.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, ids, paged)
+
+	_, code = get("cursor=not-a-cursor")
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = get("limit=101")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Without any likes or follows the feed still has something to show
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/me/feed?as=%d", users[1].ID+1), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var fresh FeedResponse
+	json.Unmarshal(w.Body.Bytes(), &fresh)
+	assert.Len(t, fresh.Items, 7)
+}
+
+func TestFeedCursorKeepsRankingTime(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	start := time.Now()
+	reader := models.User{Username: "reader", Password: "x"}
+	db.Create(&reader)
+	for i, age := range []int{0, 3, 8, 15, 30, 60} {
+		db.Create(&models.Quote{Content: fmt.Sprintf("Quote number %d", i), Author: "Someone", CreatedAt: start.Add(-time.Duration(age) * 24 * time.Hour)})
+	}
+
+	h := NewFeedHandler(db, NewRelatedHandler(db))
+	now := start
+	h.now = func() time.Time { return now }
+	r := setupFeedRouter(h)
+	get := func(query string) FeedResponse {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/me/feed?as=%d&%s", reader.ID, query), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response FeedResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+	var ranked []uint
+	for _, item := range get("").Items {
+		ranked = append(ranked, item.ID)
+	}
+	require.Len(t, ranked, 6)
+
+	// Scores decay while the reader pages, but the pages still fit together
+	var paged []uint
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		feed := get("limit=2&cursor=" + cursor)
+		for _, item := range feed.Items {
+			paged = append(paged, item.ID)
+		}
+		if cursor = feed.NextCursor; cursor == "" {
+			break
+		}
+		now = now.Add(45 * 24 * time.Hour)
+	}
+	assert.Equal(t, ranked, paged)
+}
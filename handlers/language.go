@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"Qoute-backend/config"
+	"Qoute-backend/langdetect"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// languageTagPattern matches the BCP 47 tags we accept: a 2-3 letter
+// language followed by optional script, region or variant subtags
+var languageTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// normalizeLanguageTag returns the canonical casing of a language tag, e.g.
+// pt-br becomes pt-BR and zh-hant becomes zh-Hant
+func normalizeLanguageTag(tag string) (string, bool) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if !languageTagPattern.MatchString(tag) {
+		return "", false
+	}
+
+	parts := strings.Split(tag, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i])
+		case 4:
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		default:
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-"), true
+}
+
+// primaryLanguage returns the language subtag of a tag, e.g. en for en-GB
+func primaryLanguage(tag string) string {
+	primary, _, _ := strings.Cut(tag, "-")
+	return strings.ToLower(primary)
+}
+
+// settleQuoteLanguage normalizes or detects the language of a quote and
+// resolves the original it translates. Translations of translations are
+// linked to the original. Problems are returned by field name.
+func settleQuoteLanguage(db *gorm.DB, quote *models.Quote) (map[string]string, error) {
+	problems := map[string]string{}
+
+	if quote.Language == "" || quote.Language == langdetect.Undetermined {
+		quote.Language = langdetect.Detect(quote.Content)
+	} else if tag, ok := normalizeLanguageTag(quote.Language); ok {
+		quote.Language = tag
+	} else {
+		problems["language"] = "must be a language tag such as en or pt-BR"
+	}
+
+	if quote.TranslationOf != nil {
+		var original models.Quote
+		err := db.Select("id", "translation_of").First(&original, *quote.TranslationOf).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problems["translation_of"] = "original quote not found"
+		case err != nil:
+			return nil, err
+		case original.TranslationOf != nil:
+			quote.TranslationOf = original.TranslationOf
+		}
+		if quote.ID != 0 {
+			var translations int64
+			if err := db.Model(&models.Quote{}).Where("translation_of = ?", quote.ID).Count(&translations).Error; err != nil {
+				return nil, err
+			}
+			switch {
+			case *quote.TranslationOf == quote.ID:
+				problems["translation_of"] = "a quote cannot be a translation of itself"
+			case translations > 0:
+				problems["translation_of"] = "an original with translations cannot become a translation"
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// parseAcceptLanguage returns the tags of an Accept-Language header ordered
+// by preference. Wildcards and tags with q=0 are left out.
+func parseAcceptLanguage(header string) []string {
+	type preference struct {
+		tag     string
+		quality float64
+	}
+
+	var preferences []preference
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		tag, ok := normalizeLanguageTag(tag)
+		if !ok || quality <= 0 {
+			continue
+		}
+		preferences = append(preferences, preference{tag, quality})
+	}
+
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+	tags := make([]string, len(preferences))
+	for i, preference := range preferences {
+		tags[i] = preference.tag
+	}
+	return tags
+}
+
+// bestTranslation picks the quote whose language best matches the
+// preferences, trying an exact tag match before a match on the language
+// alone. The first candidate wins ties and is the fallback.
+func bestTranslation(candidates []models.Quote, preferences []string) int {
+	for _, preference := range preferences {
+		for i, candidate := range candidates {
+			if strings.EqualFold(candidate.Language, preference) {
+				return i
+			}
+		}
+		for i, candidate := range candidates {
+			if primaryLanguage(candidate.Language) == primaryLanguage(preference) {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// translationGroup is a scope selecting an original quote and all of its
+// translations
+func translationGroup(originalID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("quotes.id = ? OR quotes.translation_of = ?", originalID, originalID)
+	}
+}
+
+// originalID returns the ID of the quote a quote translates, or its own ID
+func originalID(quote models.Quote) uint {
+	if quote.TranslationOf != nil {
+		return *quote.TranslationOf
+	}
+	return quote.ID
+}
+
+// GetTranslations lists a quote's original and all of its translations
+func GetTranslations(c *gin.Context) {
+	viewer, err := loadQuoteViewer(c, config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var quote models.Quote
+	if err := config.DB.Scopes(viewer.visible).First(&quote, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	var quotes []models.Quote
+	if err := config.DB.Scopes(viewer.visible, translationGroup(originalID(quote)), withResponseRelations).Order("quotes.id asc").Find(&quotes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]QuoteResponse, len(quotes))
+	for i, quote := range quotes {
+		response[i] = viewer.response(quote)
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"fr-CH", "fr", "en"}, parseAcceptLanguage("en;q=0.5, fr-ch, fr;q=0.9, *;q=0.1, de;q=0"))
+	assert.Empty(t, parseAcceptLanguage(""))
+}
+
+func TestSettleQuoteLanguageErrors(t *testing.T) {
+	setupInMemoryDB()
+	db := config.DB
+	original := models.Quote{Content: "Cogito, ergo sum.", Author: "Descartes"}
+	db.Create(&original)
+
+	// A missing original is a problem with the quote, not an error
+	quote := models.Quote{ID: original.ID + 1, Content: "I think, therefore I am.", TranslationOf: ptr(original.ID + 100)}
+	problems, err := settleQuoteLanguage(db, &quote)
+	assert.NoError(t, err)
+	assert.Contains(t, problems, "translation_of")
+
+	// Failing queries are reported, whether loading the original or
+	// counting translations
+	for name, failing := range map[string]func(tx *gorm.DB) bool{
+		"original":     func(tx *gorm.DB) bool { _, counting := tx.Statement.Dest.(*int64); return !counting },
+		"translations": func(tx *gorm.DB) bool { _, counting := tx.Statement.Dest.(*int64); return counting },
+	} {
+		db.Callback().Query().Before("gorm:query").Register("test:fail", func(tx *gorm.DB) {
+			if failing(tx) {
+				tx.AddError(errors.New("database went away"))
+			}
+		})
+		quote := models.Quote{ID: original.ID + 1, Content: "I think, therefore I am.", TranslationOf: ptr(original.ID)}
+		_, err := settleQuoteLanguage(db, &quote)
+		assert.Error(t, err, name)
+		db.Callback().Query().Remove("test:fail")
+	}
+}
+
+func TestQuoteLanguagesAndTranslations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	user := models.User{Username: "translator", Password: "hashed", Role: models.RoleModerator}
+	db.Create(&user)
+
+	r := gin.Default()
+	r.POST("/quotes", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		CreateQuote(c)
+	})
+	r.GET("/quotes", GetQuotes)
+	r.GET("/quotes/:id", GetQuote)
+	r.GET("/quotes/:id/translations", GetTranslations)
+
+	create := func(body map[string]interface{}) (*httptest.ResponseRecorder, models.Quote) {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/quotes", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var quote models.Quote
+		json.Unmarshal(w.Body.Bytes(), &quote)
+		return w, quote
+	}
+
+	// The language is detected when omitted
+	w, original := create(map[string]interface{}{"content": "Je pense, donc je suis.", "author": "Descartes"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "fr", original.Language)
+
+	w, english := create(map[string]interface{}{"content": "I think, therefore I am.", "author": "Descartes", "language": "en-gb", "translation_of": original.ID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "en-GB", english.Language)
+
+	// Translations of translations are linked to the original
+	w, latin := create(map[string]interface{}{"content": "Cogito, ergo sum.", "author": "Descartes", "language": "la", "translation_of": english.ID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, original.ID, *latin.TranslationOf)
+
+	w, _ = create(map[string]interface{}{"content": "x", "author": "y", "language": "not a language"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = create(map[string]interface{}{"content": "x", "author": "y", "translation_of": 9999})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	get := func(path, acceptLanguage string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// lang=en matches regional variants
+	var quotes []QuoteResponse
+	json.Unmarshal(get("/quotes?lang=en", "").Body.Bytes(), &quotes)
+	assert.Len(t, quotes, 1)
+	assert.Equal(t, english.ID, quotes[0].ID)
+
+	// The best translation is picked from Accept-Language
+	w = get(fmt.Sprintf("/quotes/%d", original.ID), "de, en-US;q=0.8")
+	var quote QuoteResponse
+	json.Unmarshal(w.Body.Bytes(), &quote)
+	assert.Equal(t, english.ID, quote.ID)
+	assert.Equal(t, "en-GB", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Accept-Language, Authorization", w.Header().Get("Vary"))
+
+	// The requested quote is kept when it matches or nothing does
+	json.Unmarshal(get(fmt.Sprintf("/quotes/%d", latin.ID), "la, fr").Body.Bytes(), &quote)
+	assert.Equal(t, latin.ID, quote.ID)
+	json.Unmarshal(get(fmt.Sprintf("/quotes/%d", latin.ID), "ja").Body.Bytes(), &quote)
+	assert.Equal(t, latin.ID, quote.ID)
+	json.Unmarshal(get(fmt.Sprintf("/quotes/%d?translate=false", original.ID), "en").Body.Bytes(), &quote)
+	assert.Equal(t, original.ID, quote.ID)
+
+	json.Unmarshal(get(fmt.Sprintf("/quotes/%d/translations", latin.ID), "").Body.Bytes(), &quotes)
+	assert.Len(t, quotes, 3)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ModerationHandler struct {
+	db *gorm.DB
+}
+
+func NewModerationHandler(db *gorm.DB) *ModerationHandler {
+	return &ModerationHandler{db: db}
+}
+
+// RejectQuoteInput is the body used to reject a quote
+type RejectQuoteInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// VerifyCitationInput is the body used to set how far a citation checks out
+type VerifyCitationInput struct {
+	Verification string `json:"verification" binding:"required"`
+}
+
+// isModeratorRole reports whether a role can moderate quotes
+func isModeratorRole(role string) bool {
+	return role == models.RoleModerator || role == models.RoleAdmin
+}
+
+// initialQuoteStatus decides whether a new quote from a user needs review.
+// Moderators, admins and users with enough approved quotes skip the queue.
+func initialQuoteStatus(db *gorm.DB, user models.User) (string, error) {
+	if isModeratorRole(user.Role) {
+		return models.QuoteStatusApproved, nil
+	}
+
+	threshold := config.AutoApproveThreshold()
+	if threshold == 0 {
+		return models.QuoteStatusPending, nil
+	}
+
+	var approved int64
+	if err := db.Model(&models.Quote{}).Where("user_id = ? AND status = ?", user.ID, models.QuoteStatusApproved).Count(&approved).Error; err != nil {
+		return "", err
+	}
+	if approved >= int64(threshold) {
+		return models.QuoteStatusApproved, nil
+	}
+	return models.QuoteStatusPending, nil
+}
+
+// GetQueue lists quotes waiting for moderation, oldest first. Rejected
+// quotes can be listed with status=rejected.
+func (h *ModerationHandler) GetQueue(c *gin.Context) {
+	status := c.DefaultQuery("status", models.QuoteStatusPending)
+	if status != models.QuoteStatusPending && status != models.QuoteStatusRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending or rejected"})
+		return
+	}
+
+	var quotes []models.Quote
+	if err := h.db.Where("status = ?", status).Order("created_at asc").Find(&quotes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load moderation queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quotes)
+}
+
+// ApproveQuote publishes a quote
+func (h *ModerationHandler) ApproveQuote(c *gin.Context) {
+	h.moderate(c, models.QuoteStatusApproved, "")
+}
+
+// RejectQuote rejects a quote with a reason shown to the submitter
+func (h *ModerationHandler) RejectQuote(c *gin.Context) {
+	var input RejectQuoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.moderate(c, models.QuoteStatusRejected, input.Reason)
+}
+
+// moderate records a moderation decision on the quote in the URL
+func (h *ModerationHandler) moderate(c *gin.Context, status, reason string) {
+	quoteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quote ID"})
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.First(&quote, quoteID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	moderatorID := c.GetUint("user_id")
+	now := time.Now()
+	err = h.db.Model(&quote).Updates(map[string]interface{}{
+		"status":           status,
+		"rejection_reason": reason,
+		"moderated_by":     moderatorID,
+		"moderated_at":     now,
+		"version":          bumpVersion,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate quote"})
+		return
+	}
+	if err := h.db.First(&quote, quote.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load moderated quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// VerifyCitation sets the verification status of a quote's citation.
+// Unlike edits it works on quotes with votes, since the quote and its
+// citation stay the same.
+func (h *ModerationHandler) VerifyCitation(c *gin.Context) {
+	quoteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quote ID"})
+		return
+	}
+	var input VerifyCitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch input.Verification {
+	case models.CitationUnverified, models.CitationVerified, models.CitationDisputed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "verification must be unverified, verified or disputed"})
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.Scopes(withResponseRelations).First(&quote, quoteID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+	if preconditionFailed(c, quote) {
+		return
+	}
+
+	result := h.db.Model(&quote).Where("version = ?", quote.Version).Updates(map[string]interface{}{
+		"source_verification": input.Verification,
+		"version":             bumpVersion,
+	})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify citation"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Quote has been modified"})
+		return
+	}
+	if err := h.db.First(&quote, quote.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load moderated quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
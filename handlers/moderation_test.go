@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/middleware"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestModerationQueue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	author := models.User{Username: "submitter", Password: "hashed"}
+	db.Create(&author)
+	other := models.User{Username: "bystander", Password: "hashed"}
+	db.Create(&other)
+	moderator := models.User{Username: "moderator", Password: "hashed", Role: models.RoleModerator}
+	db.Create(&moderator)
+	authorToken, _ := middleware.GenerateJWT(author.ID)
+	otherToken, _ := middleware.GenerateJWT(other.ID)
+	moderatorToken, _ := middleware.GenerateJWT(moderator.ID)
+
+	r := gin.Default()
+	moderationHandler := NewModerationHandler(db)
+	authed := r.Group("/")
+	authed.Use(middleware.AuthMiddleware())
+	authed.POST("/quotes", CreateQuote)
+	public := r.Group("/")
+	public.Use(middleware.OptionalAuthMiddleware())
+	public.GET("/quotes/:id", GetQuote)
+	moderation := r.Group("/moderation")
+	moderation.Use(middleware.AuthMiddleware(), middleware.RequireRole(db, models.RoleModerator, models.RoleAdmin))
+	moderation.GET("/queue", moderationHandler.GetQueue)
+	moderation.POST("/quotes/:id/approve", moderationHandler.ApproveQuote)
+	moderation.POST("/quotes/:id/reject", moderationHandler.RejectQuote)
+
+	serve := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// New quotes from regular users wait for review, whatever the client sends
+	w := serve("POST", "/quotes", authorToken, map[string]string{"content": "pending", "author": "someone", "status": "approved"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var quote models.Quote
+	json.Unmarshal(w.Body.Bytes(), &quote)
+	assert.Equal(t, models.QuoteStatusPending, quote.Status)
+	path := fmt.Sprintf("/quotes/%d", quote.ID)
+
+	// Only the submitter and moderators can see it
+	assert.Equal(t, http.StatusOK, serve("GET", path, authorToken, nil).Code)
+	assert.Equal(t, http.StatusOK, serve("GET", path, moderatorToken, nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", path, otherToken, nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", path, "", nil).Code)
+
+	// Regular users cannot moderate
+	assert.Equal(t, http.StatusForbidden, serve("GET", "/moderation/queue", otherToken, nil).Code)
+
+	w = serve("GET", "/moderation/queue", moderatorToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var queue []models.Quote
+	json.Unmarshal(w.Body.Bytes(), &queue)
+	assert.Len(t, queue, 1)
+
+	// Rejections need a reason
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/moderation"+path+"/reject", moderatorToken, map[string]string{}).Code)
+	w = serve("POST", "/moderation"+path+"/reject", moderatorToken, RejectQuoteInput{Reason: "Misattributed"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &quote)
+	assert.Equal(t, models.QuoteStatusRejected, quote.Status)
+	assert.Equal(t, "Misattributed", quote.RejectionReason)
+
+	// Approval publishes the quote
+	assert.Equal(t, http.StatusOK, serve("POST", "/moderation"+path+"/approve", moderatorToken, nil).Code)
+	assert.Equal(t, http.StatusOK, serve("GET", path, otherToken, nil).Code)
+	assert.Equal(t, http.StatusOK, serve("GET", path, "", nil).Code)
+
+	// Moderators' own quotes are approved straight away
+	w = serve("POST", "/quotes", moderatorToken, map[string]string{"content": "trusted", "author": "someone"})
+	json.Unmarshal(w.Body.Bytes(), &quote)
+	assert.Equal(t, models.QuoteStatusApproved, quote.Status)
+}
+
+func TestModerateReloadFails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	moderator := models.User{Username: "moderator", Password: "x", Role: models.RoleModerator}
+	db.Create(&moderator)
+	quote := models.Quote{Content: "pending", Author: "someone", Status: models.QuoteStatusPending}
+	db.Create(&quote)
+
+	// Fail every query once the quote has been updated
+	updated := false
+	db.Callback().Update().After("gorm:update").Register("test:mark_updated", func(tx *gorm.DB) {
+		updated = true
+	})
+	db.Callback().Query().Before("gorm:query").Register("test:fail_reload", func(tx *gorm.DB) {
+		if updated {
+			tx.AddError(errors.New("database went away"))
+		}
+	})
+	defer db.Callback().Update().Remove("test:mark_updated")
+	defer db.Callback().Query().Remove("test:fail_reload")
+
+	r := gin.Default()
+	r.POST("/moderation/quotes/:id/approve", func(c *gin.Context) {
+		c.Set("user_id", moderator.ID)
+		NewModerationHandler(db).ApproveQuote(c)
+	})
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/moderation/quotes/%d/approve", quote.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestAutoApproveThreshold(t *testing.T) {
+	setupQuoteTestDB()
+	db := config.DB
+	os.Setenv("AUTO_APPROVE_THRESHOLD", "1")
+	defer os.Unsetenv("AUTO_APPROVE_THRESHOLD")
+
+	user := models.User{Username: "regular", Password: "hashed"}
+	db.Create(&user)
+
+	status, err := initialQuoteStatus(db, user)
+	assert.NoError(t, err)
+	assert.Equal(t, models.QuoteStatusPending, status)
+
+	db.Create(&models.Quote{Content: "accepted", Author: "a", UserID: &user.ID, Status: models.QuoteStatusApproved})
+	status, err = initialQuoteStatus(db, user)
+	assert.NoError(t, err)
+	assert.Equal(t, models.QuoteStatusApproved, status)
+}
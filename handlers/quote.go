@@ -1,21 +1,159 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
+	"reflect"
+	"strings"
+	"time"
 
 	"Qoute-backend/config"
+	"Qoute-backend/langdetect"
 	"Qoute-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-// CreateQuote handles the creation of a new quote
+// QuoteInput lists the quote fields clients can write. Everything else, like
+// the ID, submitter, votes and moderation state, is managed by the server.
+type QuoteInput struct {
+	Content       string          `json:"content"`
+	Author        string          `json:"author"`
+	Source        models.Citation `json:"source"`
+	Language      string          `json:"language"`
+	TranslationOf *uint           `json:"translation_of"`
+	PublishAt     *time.Time      `json:"publish_at"`
+}
+
+// quoteInputOf returns the writable fields of a quote. Pointers are copied,
+// so binding a request into the input leaves the quote untouched.
+func quoteInputOf(quote models.Quote) QuoteInput {
+	input := QuoteInput{
+		Content:  quote.Content,
+		Author:   quote.Author,
+		Source:   quote.Source,
+		Language: quote.Language,
+	}
+	if quote.Source.Year != nil {
+		year := *quote.Source.Year
+		input.Source.Year = &year
+	}
+	if quote.TranslationOf != nil {
+		translationOf := *quote.TranslationOf
+		input.TranslationOf = &translationOf
+	}
+	if quote.PublishAt != nil {
+		publishAt := *quote.PublishAt
+		input.PublishAt = &publishAt
+	}
+	return input
+}
+
+// applyTo copies the writable fields onto a quote
+func (input QuoteInput) applyTo(quote *models.Quote) {
+	quote.Content = input.Content
+	quote.Author = input.Author
+	quote.Source = input.Source
+	quote.Language = input.Language
+	quote.TranslationOf = input.TranslationOf
+	quote.PublishAt = nil
+	if input.PublishAt != nil {
+		// Stored times are local, so store in local time to compare them
+		publishAt := input.PublishAt.In(time.Local)
+		quote.PublishAt = &publishAt
+	}
+}
+
+// validatePublishAt checks the scheduled publication of an edited quote,
+// which cannot change once the quote went live
+func validatePublishAt(previous *time.Time, quote models.Quote) map[string]string {
+	problems := map[string]string{}
+	if quote.PublishedAt == nil {
+		return problems
+	}
+	changed := (previous == nil) != (quote.PublishAt == nil) ||
+		(previous != nil && !previous.Equal(*quote.PublishAt))
+	if changed {
+		problems["publish_at"] = "cannot be changed after the quote was published"
+	}
+	return problems
+}
+
+// validateQuoteEdit checks a quote edited with PUT or PATCH, and settles its
+// citation verification and language. It returns the problems by field name;
+// an empty map means the edit can be saved.
+func validateQuoteEdit(previous models.Quote, quote *models.Quote, editor models.User) (map[string]string, error) {
+	problems := validateCitation(quote.Source)
+	for field, problem := range validatePublishAt(previous.PublishAt, *quote) {
+		problems[field] = problem
+	}
+	if strings.TrimSpace(quote.Content) == "" {
+		problems["content"] = "is required"
+	}
+	if strings.TrimSpace(quote.Author) == "" {
+		problems["author"] = "is required"
+	}
+	settleCitationVerification(&quote.Source, previous.Source, isModeratorRole(editor.Role))
+	languageProblems, err := settleQuoteLanguage(config.DB, quote)
+	if err != nil {
+		return nil, err
+	}
+	for field, problem := range languageProblems {
+		problems[field] = problem
+	}
+	return problems, nil
+}
+
+// CreateQuote handles the creation of a new quote. Quotes wait in the
+// moderation queue unless the submitter is trusted.
 func CreateQuote(c *gin.Context) {
-	var quote models.Quote
-	if err := c.ShouldBindJSON(&quote); err != nil {
+	var input QuoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	var quote models.Quote
+	input.applyTo(&quote)
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var submitter models.User
+	if err := config.DB.First(&submitter, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if problems := validateCitation(quote.Source); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid citation", "fields": problems})
+		return
+	}
+	settleCitationVerification(&quote.Source, models.Citation{}, isModeratorRole(submitter.Role))
+	problems, err := settleQuoteLanguage(config.DB, &quote)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid language", "fields": problems})
+		return
+	}
+
+	status, err := initialQuoteStatus(config.DB, submitter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The submitter and moderation state are never taken from the client
+	quote.UserID = &submitter.ID
+	quote.Status = status
+	quote.RejectionReason = ""
+	quote.ModeratedBy = nil
+	quote.ModeratedAt = nil
 
 	result := config.DB.Create(&quote)
 	if result.Error != nil {
@@ -29,38 +167,35 @@ func CreateQuote(c *gin.Context) {
 // QuoteResponse represents a quote with its vote count
 type QuoteResponse struct {
 	models.Quote
-	VoteCount int `json:"voteCount"`
+	VoteCount    int   `json:"voteCount"`
+	CommentCount int   `json:"commentCount"`
+	HasVoted     *bool `json:"has_voted,omitempty"` // Only set for signed in users
 }
 
 // GetQuotes returns all quotes with their vote counts
 func GetQuotes(c *gin.Context) {
 	var quotes []models.Quote
 
-	// Query params
-	author := c.Query("author")
-	search := c.Query("search")
-	sortBy := c.DefaultQuery("sortBy", "created_at")
-	order := c.DefaultQuery("order", "desc")
-
-	db := config.DB.Preload("Votes") // Preload the Votes relationship
-
-	// Filter by author
-	if author != "" {
-		db = db.Where("author = ?", author)
+	viewer, err := loadQuoteViewer(c, config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Search in content or author
-	if search != "" {
-		like := "%" + search + "%"
-		db = db.Where("content LIKE ? OR author LIKE ?", like, like)
+	// Query params
+	filters, err := quoteFilters(c, viewer)
+	if err != nil {
+		respondWithFilterError(c, err)
+		return
 	}
-
-	// Sorting
-	if sortBy != "" && (order == "asc" || order == "desc") {
-		db = db.Order(sortBy + " " + order)
+	order, err := quoteOrder(c)
+	if err != nil {
+		respondWithFilterError(c, err)
+		return
 	}
 
-	if err := db.Find(&quotes).Error; err != nil {
+	db := config.DB.Scopes(withResponseRelations) // Preload the Votes and Comments relationships
+	if err := db.Scopes(viewer.visible, filters).Order(order).Find(&quotes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -68,41 +203,71 @@ func GetQuotes(c *gin.Context) {
 	// Convert to response format with vote counts
 	response := make([]QuoteResponse, len(quotes))
 	for i, quote := range quotes {
-		response[i] = QuoteResponse{
-			Quote:     quote,
-			VoteCount: len(quote.Votes),
-		}
+		response[i] = viewer.response(quote)
 	}
 
-	c.JSON(http.StatusOK, response)
+	body, err := json.Marshal(response)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Vary", "Authorization")
+	if notModified(c, bodyETag(body)) {
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
 }
 
-// GetQuote returns a single quote by ID with its vote count
+// GetQuote returns a single quote by ID with its vote count. When the
+// request has an Accept-Language header, the translation that best matches
+// it is returned instead, unless translate=false.
 func GetQuote(c *gin.Context) {
 	id := c.Param("id")
 	var quote models.Quote
 
-	if err := config.DB.Preload("Votes").First(&quote, id).Error; err != nil {
+	viewer, err := loadQuoteViewer(c, config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Scopes(viewer.visible, withResponseRelations).First(&quote, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
 		return
 	}
 
-	response := QuoteResponse{
-		Quote:     quote,
-		VoteCount: len(quote.Votes),
+	c.Header("Vary", "Accept-Language, Authorization")
+	if preferences := parseAcceptLanguage(c.GetHeader("Accept-Language")); len(preferences) > 0 && c.Query("translate") != "false" {
+		var translations []models.Quote
+		if err := config.DB.Scopes(viewer.visible, translationGroup(originalID(quote))).
+			Where("quotes.id <> ?", quote.ID).Scopes(withResponseRelations).Order("quotes.id asc").Find(&translations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// The requested quote comes first so it wins when it matches as well
+		candidates := append([]models.Quote{quote}, translations...)
+		quote = candidates[bestTranslation(candidates, preferences)]
+	}
+	if quote.Language != langdetect.Undetermined {
+		c.Header("Content-Language", quote.Language)
+	}
+	if notModified(c, quoteETag(quote)) {
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, viewer.response(quote))
 }
 
-// UpdateQuote updates an existing quote
+// UpdateQuote updates an existing quote. Only the submitter and moderators
+// can update it. With an If-Match header the update only happens if the
+// quote has not changed since it was read.
 func UpdateQuote(c *gin.Context) {
-	id := c.Param("id")
-	var quote models.Quote
-
 	// First, find the quote with its votes
-	if err := config.DB.Preload("Votes").First(&quote, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+	quote, editor, ok := loadEditableQuote(c)
+	if !ok {
+		return
+	}
+	if preconditionFailed(c, quote) {
 		return
 	}
 
@@ -115,28 +280,95 @@ func UpdateQuote(c *gin.Context) {
 		return
 	}
 
-	// Then update it
-	if err := c.ShouldBindJSON(&quote); err != nil {
+	// Then update it. Fields missing from the body keep their value.
+	previous := quote
+	input := quoteInputOf(quote)
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	input.applyTo(&quote)
 
-	if err := config.DB.Save(&quote).Error; err != nil {
+	problems, err := validateQuoteEdit(previous, &quote, editor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fields", "fields": problems})
+		return
+	}
+
+	saveQuoteEdit(c, previous, quote, editor)
+}
+
+// loadEditableQuote loads the quote of the request with its votes, and the
+// user editing it. Only the submitter and moderators can change a quote, and
+// only if they can see it. It responds with an error and returns false
+// otherwise.
+func loadEditableQuote(c *gin.Context) (models.Quote, models.User, bool) {
+	var quote models.Quote
+	var editor models.User
+	if err := config.DB.First(&editor, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return quote, editor, false
+	}
+	viewer := quoteViewer{userID: editor.ID, role: editor.Role, authenticated: true}
+	if err := config.DB.Scopes(viewer.visible, withResponseRelations).First(&quote, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return quote, editor, false
+	}
+	if !viewer.canModerate() && (quote.UserID == nil || *quote.UserID != editor.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only change your own quotes"})
+		return quote, editor, false
+	}
+	return quote, editor, true
+}
+
+// saveQuoteEdit saves the writable fields of an edited quote and bumps its
+// version. The version check makes the update fail if someone else changed
+// the quote after it was read. Approved quotes edited by anyone but a
+// moderator go back to the moderation queue when their text changed.
+func saveQuoteEdit(c *gin.Context, previous, quote models.Quote, editor models.User) {
+	if quote.Status == models.QuoteStatusApproved && !isModeratorRole(editor.Role) && needsReview(previous, quote) {
+		quote.Status = models.QuoteStatusPending
+	}
+	previousVersion := quote.Version
+	quote.Version++
+	result := config.DB.Model(&quote).Where("version = ?", previousVersion).
+		Select("content", "author", "author_key", "source_work_title", "source_year", "source_page", "source_url", "source_license", "source_verification", "language", "translation_of", "publish_at", "status", "version", "updated_at").
+		Updates(&quote)
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update quote"})
 		return
 	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Quote has been modified"})
+		return
+	}
 
+	c.Header("ETag", quoteETag(quote))
 	c.JSON(http.StatusOK, quote)
 }
 
-// DeleteQuote deletes a quote if it has no votes
-func DeleteQuote(c *gin.Context) {
-	id := c.Param("id")
-	var quote models.Quote
+// needsReview reports whether an edit changed what moderators review: the
+// text, author or citation of a quote
+func needsReview(previous, quote models.Quote) bool {
+	before, after := previous.Source, quote.Source
+	before.Verification, after.Verification = "", ""
+	return quote.Content != previous.Content || quote.Author != previous.Author || !reflect.DeepEqual(before, after)
+}
 
+// DeleteQuote deletes a quote if it has no votes. Like UpdateQuote, only
+// the submitter and moderators can delete it, and it honours If-Match. The
+// delete fails with 412 if the quote changed or got votes meanwhile.
+func DeleteQuote(c *gin.Context) {
 	// Find the quote with its votes
-	if err := config.DB.Preload("Votes").First(&quote, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+	quote, _, ok := loadEditableQuote(c)
+	if !ok {
+		return
+	}
+	if preconditionFailed(c, quote) {
 		return
 	}
 
@@ -149,11 +381,16 @@ func DeleteQuote(c *gin.Context) {
 		return
 	}
 
-	// Delete the quote
-	if err := config.DB.Delete(&quote).Error; err != nil {
+	// Delete the quote, unless it changed or got votes since it was read
+	result := config.DB.Where("version = ? AND NOT EXISTS (SELECT 1 FROM votes WHERE votes.quote_id = quotes.id)", quote.Version).Delete(&quote)
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete quote"})
 		return
 	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Quote has been modified"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Quote deleted successfully"})
 } 
\ No newline at end of file
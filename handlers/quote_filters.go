@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"Qoute-backend/config"
+	"Qoute-backend/filter"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Limits on list filters
+const (
+	maxFilterIDs     = 100
+	maxFilterAuthors = 50
+)
+
+// SQL for the counts quotes can be filtered and sorted by
+const (
+	voteCountSQL    = "(SELECT COUNT(*) FROM votes AS counted_votes WHERE counted_votes.quote_id = quotes.id)"
+	commentCountSQL = "(SELECT COUNT(*) FROM comments AS counted_comments WHERE counted_comments.quote_id = quotes.id AND counted_comments.deleted_at IS NULL)"
+)
+
+// quoteFilterFields are the fields of the filter expression language
+var quoteFilterFields = map[string]filter.Field{
+	"id":       {SQL: "quotes.id", Type: filter.Number},
+	"content":  {SQL: "quotes.content", Type: filter.String},
+	"author":   {SQL: "quotes.author", Type: filter.String},
+	"language": {SQL: "quotes.language", Type: filter.String},
+	"status":   {SQL: "quotes.status", Type: filter.String},
+	"votes":    {SQL: voteCountSQL, Type: filter.Number},
+	"comments": {SQL: commentCountSQL, Type: filter.Number},
+	"rating":   {SQL: "quotes.rating", Type: filter.Number},
+	"battles":  {SQL: "quotes.battles", Type: filter.Number},
+	"created":  {SQL: "quotes.created_at", Type: filter.Time},
+	"updated":  {SQL: "quotes.updated_at", Type: filter.Time},
+	"verified": {SQL: "(quotes.source_verification = '" + models.CitationVerified + "')", Type: filter.Bool},
+}
+
+// quoteSortColumns whitelists the sortBy values of GetQuotes
+var quoteSortColumns = map[string]string{
+	"created_at": "quotes.created_at",
+	"updated_at": "quotes.updated_at",
+	"author":     "quotes.author",
+	"content":    "quotes.content",
+	"rating":     "quotes.rating",
+	"battles":    "quotes.battles",
+	"votes":      voteCountSQL,
+	"comments":   commentCountSQL,
+}
+
+// filterError is an invalid filter query param, reported with 400
+type filterError struct {
+	param    string
+	message  string
+	position *int // Set for errors in filter expressions
+}
+
+func (e *filterError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.param, e.message)
+}
+
+// respondWithFilterError responds to an error returned by quoteFilters
+func respondWithFilterError(c *gin.Context, err error) {
+	var invalid *filterError
+	if !errors.As(err, &invalid) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response := gin.H{"error": "Invalid " + invalid.param, "detail": invalid.message}
+	if invalid.position != nil {
+		response["position"] = *invalid.position
+	}
+	c.JSON(http.StatusBadRequest, response)
+}
+
+// splitList splits a comma separated query param value. Values can be double
+// quoted to contain commas, and the param can be repeated.
+func splitList(c *gin.Context, param string) ([]string, error) {
+	var values []string
+	for _, raw := range c.QueryArray(param) {
+		reader := csv.NewReader(strings.NewReader(raw))
+		reader.TrimLeadingSpace = true
+		record, err := reader.Read()
+		if err != nil {
+			return nil, &filterError{param: param, message: "must be a comma separated list"}
+		}
+		for _, value := range record {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values, nil
+}
+
+// quoteFilters reads the filter query params shared by the quote listing
+// endpoints and returns them as a scope. Invalid params are reported as a
+// *filterError.
+func quoteFilters(c *gin.Context, viewer quoteViewer) (func(db *gorm.DB) *gorm.DB, error) {
+	var conditions []func(db *gorm.DB) *gorm.DB
+	where := func(query string, args ...interface{}) {
+		conditions = append(conditions, func(db *gorm.DB) *gorm.DB {
+			return db.Where(query, args...)
+		})
+	}
+
+	// Filter by author
+	if author := c.Query("author"); author != "" {
+		where("quotes.author = ?", author)
+	}
+
+	// Search in content or author
+	if search := c.Query("search"); search != "" {
+		like := "%" + filter.EscapeLike(search) + "%"
+		where(`quotes.content LIKE ? ESCAPE '\' OR quotes.author LIKE ? ESCAPE '\'`, like, like)
+	}
+
+	// Filter by citation verification
+	switch c.Query("verified") {
+	case "true":
+		where("quotes.source_verification = ?", models.CitationVerified)
+	case "false":
+		where("quotes.source_verification <> ?", models.CitationVerified)
+	}
+
+	// Filter by language, where lang=en also matches regional tags like en-GB
+	if lang := c.Query("lang"); lang != "" {
+		if tag, ok := normalizeLanguageTag(lang); ok {
+			where("quotes.language = ? OR quotes.language LIKE ?", tag, tag+"-%")
+		} else {
+			where("1 = 0")
+		}
+	}
+
+	// Filter by creation time
+	for param, operator := range map[string]string{"created_after": ">", "created_before": "<"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		at, err := filter.ParseTime(value)
+		if err != nil {
+			return nil, &filterError{param: param, message: "must be a date like 2024-01-31 or an RFC 3339 time"}
+		}
+		where("quotes.created_at "+operator+" ?", at)
+	}
+
+	// Filter by vote count
+	for param, operator := range map[string]string{"min_votes": ">=", "max_votes": "<="} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		votes, err := strconv.Atoi(value)
+		if err != nil || votes < 0 {
+			return nil, &filterError{param: param, message: "must be a non-negative integer"}
+		}
+		where(voteCountSQL+" "+operator+" ?", votes)
+	}
+
+	// Filter by the viewer's own votes
+	if hasVoted := c.Query("has_voted"); hasVoted != "" {
+		voted, err := strconv.ParseBool(hasVoted)
+		if err != nil {
+			return nil, &filterError{param: "has_voted", message: "must be true or false"}
+		}
+		if !viewer.authenticated {
+			return nil, &filterError{param: "has_voted", message: "requires a signed in user"}
+		}
+		query := "quotes.id IN (SELECT quote_id FROM votes WHERE voter_hash = ?)"
+		if !voted {
+			query = "quotes.id NOT IN (SELECT quote_id FROM votes WHERE voter_hash = ?)"
+		}
+		where(query, config.VoterHash(viewer.userID))
+	}
+
+	// Filter by IDs
+	ids, err := splitList(c, "ids")
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		if len(ids) > maxFilterIDs {
+			return nil, &filterError{param: "ids", message: fmt.Sprintf("must list at most %d IDs", maxFilterIDs)}
+		}
+		parsed := make([]uint64, len(ids))
+		for i, id := range ids {
+			if parsed[i], err = strconv.ParseUint(id, 10, 32); err != nil {
+				return nil, &filterError{param: "ids", message: fmt.Sprintf("%q is not a quote ID", id)}
+			}
+		}
+		where("quotes.id IN ?", parsed)
+	}
+
+	// Filter by any of several authors
+	authors, err := splitList(c, "author_in")
+	if err != nil {
+		return nil, err
+	}
+	if len(authors) > 0 {
+		if len(authors) > maxFilterAuthors {
+			return nil, &filterError{param: "author_in", message: fmt.Sprintf("must list at most %d authors", maxFilterAuthors)}
+		}
+		where("quotes.author IN ?", authors)
+	}
+
+	// Filter expression
+	if expression, ok := c.GetQuery("filter"); ok {
+		node, err := filter.Parse(expression)
+		if err == nil {
+			var query string
+			var args []interface{}
+			query, args, err = filter.Compile(node, quoteFilterFields)
+			if err == nil {
+				where(query, args...)
+			}
+		}
+		var syntaxErr *filter.Error
+		if errors.As(err, &syntaxErr) {
+			return nil, &filterError{param: "filter", message: syntaxErr.Message, position: &syntaxErr.Position}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(conditions...)
+	}, nil
+}
+
+// quoteOrder returns the ORDER BY clause for the sortBy and order params
+func quoteOrder(c *gin.Context) (string, error) {
+	sortBy := c.DefaultQuery("sortBy", "created_at")
+	column, ok := quoteSortColumns[sortBy]
+	if !ok {
+		names := make([]string, 0, len(quoteSortColumns))
+		for name := range quoteSortColumns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", &filterError{param: "sortBy", message: "must be one of " + strings.Join(names, ", ")}
+	}
+
+	order := strings.ToLower(c.DefaultQuery("order", "desc"))
+	if order != "asc" && order != "desc" {
+		return "", &filterError{param: "order", message: "must be asc or desc"}
+	}
+	return column + " " + order + ", quotes.id " + order, nil
+}
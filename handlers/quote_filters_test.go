@@ -5,10 +5,13 @@ import (
 	"Qoute-backend/middleware"
 	"Qoute-backend/models"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -84,7 +87,7 @@ func TestGetQuotesSearch(t *testing.T) {
 	token, _ := middleware.GenerateJWT(user.ID)
 
 	w := httptest.NewRecorder()
-	// Search for "the" which is in two quotes
+	// Search for "the", which is in three quotes ("therefore" matches too)
 	req, _ := http.NewRequest("GET", "/quotes?search=the", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	router.ServeHTTP(w, req)
@@ -94,10 +97,11 @@ func TestGetQuotesSearch(t *testing.T) {
 	var response []QuoteResponse
 	json.Unmarshal(w.Body.Bytes(), &response)
 
-	assert.Len(t, response, 2)
+	assert.Len(t, response, 3)
 	// Check if the response contains the expected quotes
 	foundSocrates := false
 	foundGandhi := false
+	foundDescartes := false
 	for _, quote := range response {
 		if quote.Author == "Socrates" {
 			foundSocrates = true
@@ -105,9 +109,26 @@ func TestGetQuotesSearch(t *testing.T) {
 		if quote.Author == "Mahatma Gandhi" {
 			foundGandhi = true
 		}
+		if quote.Author == "René Descartes" {
+			foundDescartes = true
+		}
 	}
 	assert.True(t, foundSocrates, "Expected to find quote by Socrates")
 	assert.True(t, foundGandhi, "Expected to find quote by Mahatma Gandhi")
+	assert.True(t, foundDescartes, "Expected to find quote by René Descartes")
+
+	// LIKE wildcards in the search are matched literally
+	config.DB.Create(&models.Quote{Content: "Give 100% or nothing_at_all.", Author: "Coach"})
+	for search, count := range map[string]int{"%": 1, "_": 1, "100%": 1, "g_ve": 0, "t%e": 0} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/quotes?search="+url.QueryEscape(search), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+
+		var found []QuoteResponse
+		json.Unmarshal(w.Body.Bytes(), &found)
+		assert.Len(t, found, count, search)
+	}
 }
 
 func TestGetQuotesSorting(t *testing.T) {
@@ -145,3 +166,159 @@ func TestGetQuotesSorting(t *testing.T) {
 	assert.Len(t, resp2, 4)
 	assert.Equal(t, "The only true wisdom is in knowing you know nothing.", resp2[0].Content)
 }
+
+// getQuotes requests /quotes with a query string as the given user
+func getQuotes(t *testing.T, router *gin.Engine, token, query string) (*httptest.ResponseRecorder, []QuoteResponse) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/quotes?"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+
+	var response []QuoteResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+// quoteIDs returns the IDs of listed quotes in order
+func quoteIDs(quotes []QuoteResponse) []uint {
+	ids := make([]uint, len(quotes))
+	for i, quote := range quotes {
+		ids[i] = quote.ID
+	}
+	return ids
+}
+
+// voteAs records a vote of the given user on a quote
+func voteAs(userID, quoteID uint) {
+	hash := config.VoterHash(userID)
+	config.DB.Create(&models.Vote{VoterHash: &hash, QuoteID: quoteID})
+}
+
+func TestGetQuotesRangeFilters(t *testing.T) {
+	router, quotes := setupTestRouterWithQuotes(t)
+
+	user := models.User{Username: "range_user", Password: "password"}
+	config.DB.Create(&user)
+	token, _ := middleware.GenerateJWT(user.ID)
+
+	// Quote 1 gets two votes, quote 3 one
+	voteAs(user.ID, quotes[0].ID)
+	voteAs(user.ID+100, quotes[0].ID)
+	voteAs(user.ID+101, quotes[2].ID)
+
+	w, response := getQuotes(t, router, token, "min_votes=1&sortBy=votes&order=desc")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uint{quotes[0].ID, quotes[2].ID}, quoteIDs(response))
+
+	_, response = getQuotes(t, router, token, "max_votes=0&sortBy=created_at&order=asc")
+	assert.Equal(t, []uint{quotes[1].ID, quotes[3].ID}, quoteIDs(response))
+
+	_, response = getQuotes(t, router, token, "has_voted=true")
+	assert.Equal(t, []uint{quotes[0].ID}, quoteIDs(response))
+
+	_, response = getQuotes(t, router, token, "has_voted=false")
+	assert.Len(t, response, 3)
+
+	// Backdate quote 2 to test the creation time filters
+	config.DB.Model(&quotes[1]).UpdateColumn("created_at", time.Date(2020, 6, 1, 12, 0, 0, 0, time.Local))
+
+	_, response = getQuotes(t, router, token, "created_before=2021-01-01")
+	assert.Equal(t, []uint{quotes[1].ID}, quoteIDs(response))
+
+	_, response = getQuotes(t, router, token, "created_after=2020-06-01T13:00:00Z&max_votes=0")
+	assert.Equal(t, []uint{quotes[3].ID}, quoteIDs(response))
+}
+
+func TestGetQuotesListFilters(t *testing.T) {
+	router, quotes := setupTestRouterWithQuotes(t)
+
+	user := models.User{Username: "list_user", Password: "password"}
+	config.DB.Create(&user)
+	token, _ := middleware.GenerateJWT(user.ID)
+
+	w, response := getQuotes(t, router, token, fmt.Sprintf("ids=%d,%d&sortBy=created_at&order=asc", quotes[3].ID, quotes[1].ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uint{quotes[1].ID, quotes[3].ID}, quoteIDs(response))
+
+	// Quoted values may contain commas and the param can be repeated
+	config.DB.Model(&quotes[2]).Update("author", "Gandhi, Mahatma")
+	query := url.Values{"author_in": {`"Gandhi, Mahatma"`, "René Descartes"}, "sortBy": {"author"}, "order": {"asc"}}
+	_, response = getQuotes(t, router, token, query.Encode())
+	assert.Equal(t, []uint{quotes[2].ID, quotes[3].ID}, quoteIDs(response))
+}
+
+func TestGetQuotesFilterExpression(t *testing.T) {
+	router, quotes := setupTestRouterWithQuotes(t)
+
+	user := models.User{Username: "expression_user", Password: "password"}
+	config.DB.Create(&user)
+	token, _ := middleware.GenerateJWT(user.ID)
+
+	voteAs(user.ID, quotes[0].ID)
+	voteAs(user.ID+100, quotes[0].ID)
+	voteAs(user.ID+101, quotes[1].ID)
+
+	tests := []struct {
+		filter string
+		want   []uint
+	}{
+		{`votes>1 and author:"Socrates"`, []uint{quotes[0].ID}},
+		{`author:Socrates and not votes>=2`, []uint{quotes[1].ID}},
+		{`votes:0 or content~"unexamined"`, []uint{quotes[1].ID, quotes[2].ID, quotes[3].ID}},
+		{`(author:Socrates or author:"Mahatma Gandhi") and votes<2`, []uint{quotes[1].ID, quotes[2].ID}},
+		{`content~"100%"`, []uint{}},
+		{`verified:false and id>=3`, []uint{quotes[2].ID, quotes[3].ID}},
+	}
+	for _, tt := range tests {
+		query := url.Values{"filter": {tt.filter}, "sortBy": {"created_at"}, "order": {"asc"}}
+		w, response := getQuotes(t, router, token, query.Encode())
+		assert.Equal(t, http.StatusOK, w.Code, tt.filter)
+		assert.Equal(t, tt.want, quoteIDs(response), tt.filter)
+	}
+}
+
+func TestGetQuotesInvalidFilters(t *testing.T) {
+	router, _ := setupTestRouterWithQuotes(t)
+
+	user := models.User{Username: "invalid_filter_user", Password: "password"}
+	config.DB.Create(&user)
+	token, _ := middleware.GenerateJWT(user.ID)
+
+	tests := []struct {
+		query    url.Values
+		param    string
+		position float64
+	}{
+		{url.Values{"filter": {`votes>5 and`}}, "filter", 11},
+		{url.Values{"filter": {`author:"Socrates`}}, "filter", 7},
+		{url.Values{"filter": {`password:x`}}, "filter", 0},
+		{url.Values{"filter": {`votes>"five"`}}, "filter", 0},
+		{url.Values{"filter": {`(votes>5`}}, "filter", 0},
+		{url.Values{"filter": {`votes>5; DROP TABLE quotes`}}, "filter", 7},
+		{url.Values{"min_votes": {"-1"}}, "min_votes", -1},
+		{url.Values{"created_after": {"yesterday"}}, "created_after", -1},
+		{url.Values{"ids": {"1,abc"}}, "ids", -1},
+		{url.Values{"has_voted": {"maybe"}}, "has_voted", -1},
+		{url.Values{"sortBy": {"password"}}, "sortBy", -1},
+		{url.Values{"order": {"sideways"}}, "order", -1},
+	}
+	for _, tt := range tests {
+		w, _ := getQuotes(t, router, token, tt.query.Encode())
+		assert.Equal(t, http.StatusBadRequest, w.Code, tt.query.Encode())
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "Invalid "+tt.param, response["error"], tt.query.Encode())
+		assert.NotEmpty(t, response["detail"], tt.query.Encode())
+		if tt.position >= 0 {
+			assert.Equal(t, tt.position, response["position"], tt.query.Encode())
+		} else {
+			assert.NotContains(t, response, "position", tt.query.Encode())
+		}
+	}
+
+	// The quotes table survived
+	var count int64
+	config.DB.Model(&models.Quote{}).Count(&count)
+	assert.Equal(t, int64(4), count)
+}
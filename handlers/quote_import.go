@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Qoute-backend/config"
+	"Qoute-backend/formats"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Import limits
+const (
+	maxImportBytes     = 10 << 20
+	maxImportRows      = 10000
+	defaultImportBatch = 100
+	maxImportBatch     = 1000
+	duplicateLookup    = 500 // Authors looked up per query when finding duplicates
+)
+
+// Import modes
+const (
+	importAtomic  = "atomic"  // Every row is saved, or none
+	importBatches = "batches" // Each batch is saved on its own
+)
+
+// Import row statuses
+const (
+	importInserted  = "inserted"
+	importValid     = "valid" // Would be inserted, but was not
+	importDuplicate = "duplicate"
+	importInvalid   = "invalid"
+	importFailed    = "failed"  // Valid, but its batch could not be saved
+	importSkipped   = "skipped" // Not a quote, like a Kindle bookmark
+)
+
+// ImportRow reports what happened to one row of an import
+type ImportRow struct {
+	Row            int               `json:"row"`
+	Status         string            `json:"status"`
+	ID             uint              `json:"id,omitempty"`
+	DuplicateOf    uint              `json:"duplicate_of,omitempty"`     // Existing quote
+	DuplicateOfRow int               `json:"duplicate_of_row,omitempty"` // Earlier row of the same import
+	Reason         string            `json:"reason,omitempty"`           // Why the row was skipped
+	Errors         map[string]string `json:"errors,omitempty"`
+}
+
+// ImportResult reports an import row by row
+type ImportResult struct {
+	Format     string      `json:"format"`
+	Mode       string      `json:"mode"`
+	DryRun     bool        `json:"dry_run"`
+	Total      int         `json:"total"`
+	Valid      int         `json:"valid"` // Rows that were or would be inserted
+	Inserted   int         `json:"inserted"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Skipped    int         `json:"skipped"`
+	Failed     int         `json:"failed"`
+	Rows       []ImportRow `json:"rows"`
+}
+
+// quoteImport is a row read from an import and the quote it becomes
+type quoteImport struct {
+	ImportRow
+	quote models.Quote
+}
+
+// errUnsupportedImport is returned for uploads in an unknown format
+var errUnsupportedImport = errors.New("unsupported import format")
+
+// quoteKey identifies a quote when looking for duplicates, ignoring case,
+// spacing and surrounding quotation marks
+func quoteKey(content, author string) string {
+	content = strings.Trim(strings.TrimSpace(content), "\"'“”‘’«»")
+	return strings.ToLower(strings.Join(strings.Fields(content), " ")) + "\x00" + models.AuthorKey(author)
+}
+
+// findDuplicateQuotes returns the IDs of stored quotes with the same text
+// and author as the given ones, by quote key. Candidates are looked up by
+// their stored author key, so authors match however they are cased or
+// spaced. Quotes in the trash are not counted.
+func findDuplicateQuotes(db *gorm.DB, quotes []models.Quote) (map[string]uint, error) {
+	wanted := map[string]bool{}
+	authorSet := map[string]bool{}
+	for _, quote := range quotes {
+		wanted[quoteKey(quote.Content, quote.Author)] = true
+		authorSet[models.AuthorKey(quote.Author)] = true
+	}
+	authors := make([]string, 0, len(authorSet))
+	for author := range authorSet {
+		authors = append(authors, author)
+	}
+
+	duplicates := map[string]uint{}
+	for start := 0; start < len(authors); start += duplicateLookup {
+		end := min(start+duplicateLookup, len(authors))
+		var existing []models.Quote
+		err := db.Select("id", "content", "author").Where("author_key IN ?", authors[start:end]).Order("id asc").Find(&existing).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, quote := range existing {
+			key := quoteKey(quote.Content, quote.Author)
+			if _, found := duplicates[key]; wanted[key] && !found {
+				duplicates[key] = quote.ID
+			}
+		}
+	}
+	return duplicates, nil
+}
+
+// importSource finds the uploaded file and its format. Files are sent as
+// the request body or as the "file" field of a multipart form. The format
+// query parameter wins, then formats.Detect: a known export's file name,
+// like "My Clippings.txt", then the media type, then the file extension.
+func importSource(c *gin.Context) (io.Reader, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	var source io.Reader = c.Request.Body
+	format := formats.Detect(c.GetHeader("Content-Type"), "")
+	if mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type")); mediaType == "multipart/form-data" {
+		form, err := c.Request.MultipartReader()
+		if err != nil {
+			return nil, "", err
+		}
+		for {
+			part, err := form.NextPart()
+			if err == io.EOF {
+				return nil, "", errors.New("missing file field")
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			if part.FormName() == "file" {
+				source = part
+				format = formats.Detect(part.Header.Get("Content-Type"), part.FileName())
+				break
+			}
+		}
+	}
+
+	if requested := c.Query("format"); requested != "" {
+		format = requested
+	}
+	if !formats.Supported(format) {
+		return nil, "", errUnsupportedImport
+	}
+	return source, format, nil
+}
+
+// respondWithImportError reports an upload that could not be read
+func respondWithImportError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	var syntaxErr *formats.SyntaxError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Imports are limited to %d MB", maxImportBytes>>20)})
+	case errors.Is(err, errUnsupportedImport):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported format", "formats": formats.Names})
+	case errors.As(err, &syntaxErr):
+		response := gin.H{"error": "Invalid file", "detail": syntaxErr.Msg}
+		if syntaxErr.Line > 0 {
+			response["line"] = syntaxErr.Line
+		}
+		c.JSON(http.StatusBadRequest, response)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file", "detail": err.Error()})
+	}
+}
+
+// checkImportRow validates a row as a new quote would be
+func checkImportRow(row formats.Row, submitter models.User, status string) (quoteImport, error) {
+	if row.Skipped != "" {
+		return quoteImport{ImportRow: ImportRow{Row: row.Line, Status: importSkipped, Reason: row.Skipped}}, nil
+	}
+	imported := quoteImport{ImportRow: ImportRow{Row: row.Line, Errors: row.Problems}}
+	if imported.Errors == nil {
+		imported.Errors = map[string]string{}
+	}
+	problem := func(field, message string) {
+		if _, found := imported.Errors[field]; !found {
+			imported.Errors[field] = message
+		}
+	}
+
+	// Only the writable fields are taken from the file
+	quote := &imported.quote
+	quoteInputOf(row.Quote).applyTo(quote)
+	quote.Content = strings.TrimSpace(quote.Content)
+	quote.Author = strings.TrimSpace(quote.Author)
+	if _, unreadable := imported.Errors["row"]; !unreadable {
+		if quote.Content == "" {
+			problem("content", "is required")
+		}
+		if quote.Author == "" {
+			problem("author", "is required")
+		}
+		for field, message := range validateCitation(quote.Source) {
+			problem(field, message)
+		}
+		settleCitationVerification(&quote.Source, models.Citation{}, isModeratorRole(submitter.Role))
+		languageProblems, err := settleQuoteLanguage(config.DB, quote)
+		if err != nil {
+			return quoteImport{}, err
+		}
+		for field, message := range languageProblems {
+			problem(field, message)
+		}
+	}
+	quote.UserID = &submitter.ID
+	quote.Status = status
+
+	imported.Status = importValid
+	if len(imported.Errors) > 0 {
+		imported.Status = importInvalid
+	} else {
+		imported.Errors = nil
+	}
+	return imported, nil
+}
+
+// ImportQuotes creates quotes from an uploaded CSV, JSON, NDJSON, fortune,
+// Kindle clippings or Goodreads file. Every row is validated like a new
+// quote and rows already stored, or repeated in the file, are skipped as
+// duplicates. Entries that are not quotes, like Kindle bookmarks and notes,
+// are skipped with a reason. The result reports each row. In a dry run
+// nothing is saved.
+func ImportQuotes(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+			return
+		}
+		dryRun = parsed
+	}
+	mode := c.DefaultQuery("mode", importAtomic)
+	if mode != importAtomic && mode != importBatches {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be atomic or batches"})
+		return
+	}
+	batchSize, err := strconv.Atoi(c.DefaultQuery("batch_size", strconv.Itoa(defaultImportBatch)))
+	if err != nil || batchSize < 1 || batchSize > maxImportBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "batch_size must be between 1 and 1000"})
+		return
+	}
+
+	var submitter models.User
+	if err := config.DB.First(&submitter, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	status, err := initialQuoteStatus(config.DB, submitter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, format, err := importSource(c)
+	if err != nil {
+		respondWithImportError(c, err)
+		return
+	}
+	reader, _ := formats.NewReader(format, source)
+	var imports []quoteImport
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondWithImportError(c, err)
+			return
+		}
+		if len(imports) == maxImportRows {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Imports are limited to %d quotes", maxImportRows)})
+			return
+		}
+		imported, err := checkImportRow(row, submitter, status)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		imports = append(imports, imported)
+	}
+
+	// Skip quotes already stored and repeats within the file
+	var valid []models.Quote
+	for _, imported := range imports {
+		if imported.Status == importValid {
+			valid = append(valid, imported.quote)
+		}
+	}
+	existing, err := findDuplicateQuotes(config.DB, valid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	firstRows := map[string]int{}
+	var pending []int
+	for i := range imports {
+		imported := &imports[i]
+		if imported.Status != importValid {
+			continue
+		}
+		key := quoteKey(imported.quote.Content, imported.quote.Author)
+		if id, found := existing[key]; found {
+			imported.Status, imported.DuplicateOf = importDuplicate, id
+		} else if row, found := firstRows[key]; found {
+			imported.Status, imported.DuplicateOfRow = importDuplicate, row
+		} else {
+			firstRows[key] = imported.Row
+			pending = append(pending, i)
+		}
+	}
+
+	result := ImportResult{Format: format, Mode: mode, DryRun: dryRun, Total: len(imports), Valid: len(pending)}
+	for _, imported := range imports {
+		switch imported.Status {
+		case importDuplicate:
+			result.Duplicates++
+		case importInvalid:
+			result.Invalid++
+		case importSkipped:
+			result.Skipped++
+		}
+	}
+
+	// A failed row stops an atomic import before anything is saved
+	save := !dryRun && len(pending) > 0 && (mode == importBatches || result.Invalid == 0)
+	if save {
+		saveBatch := func(tx *gorm.DB, batch []int) error {
+			quotes := make([]models.Quote, len(batch))
+			for i, index := range batch {
+				quotes[i] = imports[index].quote
+			}
+			if err := tx.Create(&quotes).Error; err != nil {
+				return err
+			}
+			for i, index := range batch {
+				imports[index].quote = quotes[i]
+			}
+			return nil
+		}
+		batches := make([][]int, 0, len(pending)/batchSize+1)
+		for start := 0; start < len(pending); start += batchSize {
+			batches = append(batches, pending[start:min(start+batchSize, len(pending))])
+		}
+
+		if mode == importAtomic {
+			err := config.DB.Transaction(func(tx *gorm.DB) error {
+				for _, batch := range batches {
+					if err := saveBatch(tx, batch); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import quotes"})
+				return
+			}
+			for _, index := range pending {
+				imports[index].Status = importInserted
+			}
+		} else {
+			for _, batch := range batches {
+				err := config.DB.Transaction(func(tx *gorm.DB) error { return saveBatch(tx, batch) })
+				for _, index := range batch {
+					if err != nil {
+						imports[index].Status = importFailed
+						imports[index].Errors = map[string]string{"row": "could not be saved: " + err.Error()}
+					} else {
+						imports[index].Status = importInserted
+					}
+				}
+			}
+		}
+	}
+
+	result.Rows = make([]ImportRow, len(imports))
+	for i, imported := range imports {
+		if imported.Status == importInserted {
+			imported.ID = imported.quote.ID
+			result.Inserted++
+		}
+		if imported.Status == importFailed {
+			result.Failed++
+		}
+		result.Rows[i] = imported.ImportRow
+	}
+
+	switch {
+	case !dryRun && mode == importAtomic && result.Invalid > 0:
+		c.JSON(http.StatusUnprocessableEntity, result)
+	case result.Inserted > 0:
+		c.JSON(http.StatusCreated, result)
+	default:
+		c.JSON(http.StatusOK, result)
+	}
+}
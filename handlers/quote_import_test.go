@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupImportRouter() *gin.Engine {
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Query("as"))
+		c.Set("user_id", uint(id))
+		c.Next()
+	})
+	r.POST("/quotes/import", ImportQuotes)
+	return r
+}
+
+// importQuotes posts a file as the request body
+func importQuotes(r *gin.Engine, query, contentType, body string) (ImportResult, *httptest.ResponseRecorder) {
+	req, _ := http.NewRequest("POST", "/quotes/import?"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var result ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return result, w
+}
+
+func TestImportCSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	admin := models.User{Username: "admin", Password: "x", Role: models.RoleAdmin}
+	db.Create(&admin)
+	existing := models.Quote{Content: "Stay hungry, stay foolish.", Author: "Steve Jobs"}
+	db.Create(&existing)
+	r := setupImportRouter()
+
+	csv := "content,author,source_work_title,source_year,language\n" +
+		"The unexamined life is not worth living.,Socrates,Apology,,en\n" +
+		"\"  “stay HUNGRY,  stay foolish.” \",steve jobs,,,\n" +
+		",Nobody,,,\n" +
+		"Knowing yourself is the beginning of all wisdom.,Aristotle,,long ago,\n" +
+		"The unexamined life is not worth living.,SOCRATES,,,\n"
+
+	// A dry run reports every row and saves nothing
+	result, w := importQuotes(r, fmt.Sprintf("as=%d&dry_run=true", admin.ID), "text/csv", csv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ImportResult{
+		Format: "csv", Mode: "atomic", DryRun: true, Total: 5, Valid: 1, Duplicates: 2, Invalid: 2,
+		Rows: []ImportRow{
+			{Row: 1, Status: "valid"},
+			{Row: 2, Status: "duplicate", DuplicateOf: existing.ID},
+			{Row: 3, Status: "invalid", Errors: map[string]string{"content": "is required"}},
+			{Row: 4, Status: "invalid", Errors: map[string]string{"source.year": "must be a whole number"}},
+			{Row: 5, Status: "duplicate", DuplicateOfRow: 1},
+		},
+	}, result)
+
+	// Invalid rows stop an atomic import
+	result, w = importQuotes(r, fmt.Sprintf("as=%d", admin.ID), "text/csv", csv)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Zero(t, result.Inserted)
+	var count int64
+	db.Model(&models.Quote{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Batches save the valid rows and skip the rest
+	result, w = importQuotes(r, fmt.Sprintf("as=%d&mode=batches&batch_size=1", admin.ID), "text/csv", csv)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 1, result.Inserted)
+	require.Equal(t, "inserted", result.Rows[0].Status)
+	var imported models.Quote
+	require.NoError(t, db.First(&imported, result.Rows[0].ID).Error)
+	assert.Equal(t, "Socrates", imported.Author)
+	assert.Equal(t, "Apology", imported.Source.WorkTitle)
+	assert.Equal(t, models.QuoteStatusApproved, imported.Status, "moderators skip the queue")
+	assert.Equal(t, admin.ID, *imported.UserID)
+	assert.Equal(t, 1500.0, imported.Rating)
+
+	// Importing again only finds duplicates
+	result, w = importQuotes(r, fmt.Sprintf("as=%d&mode=batches", admin.ID), "text/csv", csv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, result.Duplicates)
+	assert.Equal(t, imported.ID, result.Rows[0].DuplicateOf)
+}
+
+func TestImportJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	user := models.User{Username: "reader", Password: "x"}
+	db.Create(&user)
+	original := models.Quote{Content: "Where there is love there is life.", Author: "Mahatma Gandhi"}
+	db.Create(&original)
+	r := setupImportRouter()
+
+	body := fmt.Sprintf(`[
+		{"content": "Be the change that you wish to see in the world.", "author": "Mahatma Gandhi", "source": {"verification": "verified"}},
+		{"content": "Donde hay amor hay vida.", "author": "Mahatma Gandhi", "language": "es", "translation_of": %d}
+	]`, original.ID)
+	result, w := importQuotes(r, fmt.Sprintf("as=%d", user.ID), "application/json", body)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 2, result.Inserted)
+
+	var quotes []models.Quote
+	db.Order("id asc").Find(&quotes)
+	require.Len(t, quotes, 3)
+	assert.Equal(t, models.QuoteStatusPending, quotes[1].Status, "quotes from users wait for moderation")
+	assert.Equal(t, models.CitationUnverified, quotes[1].Source.Verification)
+	assert.Equal(t, "en", quotes[1].Language, "the language is detected")
+	assert.Equal(t, original.ID, *quotes[2].TranslationOf)
+
+	// NDJSON, with the format given as a query parameter
+	ndjson := "{\"content\": \"Stay hungry.\", \"author\": \"Steve Jobs\"}\n{\"content\": \"Broken\n"
+	result, w = importQuotes(r, fmt.Sprintf("as=%d&format=ndjson&mode=batches", user.ID), "text/plain", ndjson)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 1, result.Inserted)
+	assert.Contains(t, result.Rows[1].Errors["row"], "invalid JSON")
+
+	_, w = importQuotes(r, fmt.Sprintf("as=%d", user.ID), "application/json", `{"content": "x"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, w = importQuotes(r, fmt.Sprintf("as=%d", user.ID), "text/plain", "hello")
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	_, w = importQuotes(r, fmt.Sprintf("as=%d&mode=all", user.ID), "application/json", "[]")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportDuplicateAuthors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	admin := models.User{Username: "admin", Password: "x", Role: models.RoleAdmin}
+	db.Create(&admin)
+	existing := []models.Quote{
+		{Content: "Rien n'est plus dangereux qu'une idée.", Author: "Émile Zola"},
+		{Content: "Get your facts first.", Author: "Mark  Twain "},
+	}
+	db.Create(&existing)
+	assert.Equal(t, "émile zola", existing[0].AuthorKey)
+	r := setupImportRouter()
+
+	// Case and spacing of any script are ignored
+	body := `[
+		{"content": "Rien n'est plus dangereux qu'une idée.", "author": "ÉMILE ZOLA"},
+		{"content": "Get your facts first.", "author": "Mark Twain"}
+	]`
+	result, w := importQuotes(r, fmt.Sprintf("as=%d&dry_run=true", admin.ID), "application/json", body)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, result.Duplicates)
+	assert.Equal(t, existing[0].ID, result.Rows[0].DuplicateOf)
+	assert.Equal(t, existing[1].ID, result.Rows[1].DuplicateOf)
+
+	// Edited authors are found by their new name
+	existing[1].Author = "Samuel Clemens"
+	require.NoError(t, db.Model(&existing[1]).Select("author", "author_key").Updates(&existing[1]).Error)
+	result, _ = importQuotes(r, fmt.Sprintf("as=%d&dry_run=true", admin.ID), "application/json",
+		`[{"content": "Get your facts first.", "author": "samuel  clemens"}]`)
+	assert.Equal(t, 1, result.Duplicates)
+}
+
+func TestImportMultipart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	user := models.User{Username: "reader", Password: "x"}
+	db.Create(&user)
+	r := setupImportRouter()
+
+	upload := func(filename, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("note", "fields before the file are skipped")
+		file, _ := form.CreateFormFile("file", filename)
+		file.Write([]byte(content))
+		form.Close()
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/quotes/import?as=%d", user.ID), &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload("quotes.csv", "content,author\nStay hungry.,Steve Jobs\n")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = upload("quotes.jsonl", `{"content": "Be the change.", "author": "Gandhi"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = upload("quotes.csv", "content,writer\nStay hungry.,Steve Jobs\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"line":1`)
+
+	var count int64
+	db.Model(&models.Quote{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestImportKindleClippings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	admin := models.User{Username: "admin", Password: "x", Role: models.RoleAdmin}
+	db.Create(&admin)
+	existing := models.Quote{Content: "You have power over your mind - not outside events.", Author: "Marcus Aurelius"}
+	db.Create(&existing)
+	r := setupImportRouter()
+
+	clippings := "\ufeffMeditations (Marcus Aurelius)\r\n" +
+		"- Your Highlight on page 12 | location 180-181 | Added on Monday, 3 June 2024 21:04:05\r\n" +
+		"\r\n" +
+		"You have power over your mind - not outside events.\r\n" +
+		"==========\r\n" +
+		"\ufeffMeditations (Marcus Aurelius)\r\n" +
+		"- Your Bookmark on page 14 | location 201 | Added on Monday, 3 June 2024 21:10:00\r\n" +
+		"\r\n" +
+		"\r\n" +
+		"==========\r\n" +
+		"\ufeffThe Adventures of Tom Sawyer (Twain, Mark)\r\n" +
+		"- Your Highlight on page 40 | Added on Tuesday, 4 June 2024 08:00:00\r\n" +
+		"\r\n" +
+		"Work consists of whatever a body is obliged to do.\r\n" +
+		"==========\r\n"
+
+	result, w := importQuotes(r, fmt.Sprintf("as=%d&format=kindle", admin.ID), "text/plain", clippings)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	require.Len(t, result.Rows, 3)
+	assert.Equal(t, ImportRow{Row: 1, Status: "duplicate", DuplicateOf: existing.ID}, result.Rows[0])
+	assert.Equal(t, ImportRow{Row: 6, Status: "skipped", Reason: "bookmark"}, result.Rows[1])
+	assert.Equal(t, "inserted", result.Rows[2].Status)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Equal(t, 1, result.Duplicates)
+
+	var quote models.Quote
+	require.NoError(t, db.First(&quote, result.Rows[2].ID).Error)
+	assert.Equal(t, "Mark Twain", quote.Author)
+	assert.Equal(t, "The Adventures of Tom Sawyer", quote.Source.WorkTitle)
+	assert.Equal(t, "40", quote.Source.Page)
+
+	// Goodreads quotes that were already imported are duplicates
+	goodreads := "Quote,Author,Book\n" +
+		"\"“Work consists of whatever a body is obliged to do.”\",― Mark Twain,The Adventures of Tom Sawyer\n"
+	result, w = importQuotes(r, fmt.Sprintf("as=%d&format=goodreads", admin.ID), "text/csv", goodreads)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []ImportRow{{Row: 1, Status: "duplicate", DuplicateOf: quote.ID}}, result.Rows)
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"Qoute-backend/patch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// acceptedPatchTypes lists the content types PatchQuote accepts
+var acceptedPatchTypes = []string{patch.MergePatchContentType, patch.JSONPatchContentType}
+
+// writableQuoteFields lists the members a patched quote document may have,
+// with the members allowed inside object members
+var writableQuoteFields = map[string][]string{
+	"content":        nil,
+	"author":         nil,
+	"language":       nil,
+	"translation_of": nil,
+	"publish_at":     nil,
+	"source":         {"work_title", "year", "page", "url", "license", "verification"},
+}
+
+// unwritableMembers returns the members of a patched quote document that
+// are not writable
+func unwritableMembers(document map[string]interface{}) map[string]string {
+	problems := map[string]string{}
+	for name, value := range document {
+		members, writable := writableQuoteFields[name]
+		if !writable {
+			problems[name] = "is not writable"
+			continue
+		}
+		if members == nil {
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			problems[name] = "must be an object"
+			continue
+		}
+		for member := range object {
+			if !slices.Contains(members, member) {
+				problems[name+"."+member] = "is not writable"
+			}
+		}
+	}
+	return problems
+}
+
+// PatchQuote partially updates a quote with a JSON Merge Patch (RFC 7396),
+// or a JSON Patch (RFC 6902) when sent as application/json-patch+json. The
+// patch applies to the writable fields only, and invalid results are
+// rejected with 422 and the problems by field. Like UpdateQuote, only the
+// submitter and moderators can patch a quote.
+func PatchQuote(c *gin.Context) {
+	quote, editor, ok := loadEditableQuote(c)
+	if !ok {
+		return
+	}
+	if preconditionFailed(c, quote) {
+		return
+	}
+	if len(quote.Votes) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":     "Cannot update quote: it has votes",
+			"voteCount": len(quote.Votes),
+		})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read patch"})
+		return
+	}
+
+	// Patch a JSON document of the writable fields
+	var document interface{}
+	current, _ := json.Marshal(quoteInputOf(quote))
+	json.Unmarshal(current, &document)
+
+	switch c.ContentType() {
+	case patch.MergePatchContentType, "application/json":
+		var mergePatch interface{}
+		if err := json.Unmarshal(body, &mergePatch); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merge patch: " + err.Error()})
+			return
+		}
+		document = patch.Merge(document, mergePatch)
+	case patch.JSONPatchContentType:
+		var operations []patch.Operation
+		if err := json.Unmarshal(body, &operations); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON Patch: " + err.Error()})
+			return
+		}
+		document, err = patch.Apply(document, operations)
+		if errors.Is(err, patch.ErrTestFailed) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Cannot apply JSON Patch: " + err.Error()})
+			return
+		}
+	default:
+		c.Header("Accept-Patch", strings.Join(acceptedPatchTypes, ", "))
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported patch format", "supported": acceptedPatchTypes})
+		return
+	}
+
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The patched quote must be a JSON object"})
+		return
+	}
+	if problems := unwritableMembers(object); len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid fields", "fields": problems})
+		return
+	}
+
+	// Members removed by the patch are cleared
+	var input QuoteInput
+	patched, _ := json.Marshal(object)
+	if err := json.NewDecoder(bytes.NewReader(patched)).Decode(&input); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid fields", "fields": gin.H{typeErr.Field: "must be a " + typeErr.Type.String()}})
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous := quote
+	input.applyTo(&quote)
+
+	problems, err := validateQuoteEdit(previous, &quote, editor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid fields", "fields": problems})
+		return
+	}
+
+	saveQuoteEdit(c, previous, quote, editor)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchQuote(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	user := models.User{Username: "patcher", Password: "hashed"}
+	db.Create(&user)
+	quote := models.Quote{Content: "Original", Author: "someone", Language: "en", Source: models.Citation{WorkTitle: "Book", Page: "4"}, UserID: &user.ID}
+	db.Create(&quote)
+
+	r := gin.Default()
+	r.PATCH("/quotes/:id", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		PatchQuote(c)
+	})
+
+	path := fmt.Sprintf("/quotes/%d", quote.ID)
+	send := func(contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Merge patch changes only the given members, null clears one
+	w := send("application/merge-patch+json", `{"content":"Patched","source":{"page":null}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&quote, quote.ID)
+	assert.Equal(t, "Patched", quote.Content)
+	assert.Equal(t, "someone", quote.Author)
+	assert.Equal(t, "Book", quote.Source.WorkTitle)
+	assert.Empty(t, quote.Source.Page)
+	assert.EqualValues(t, 2, quote.Version)
+
+	// Server managed fields are rejected
+	w = send("application/merge-patch+json", `{"id":99,"created_at":"2000-01-01T00:00:00Z","votes":[],"source":{"isbn":"x"}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var failure struct {
+		Fields map[string]string `json:"fields"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &failure)
+	assert.Contains(t, failure.Fields, "id")
+	assert.Contains(t, failure.Fields, "created_at")
+	assert.Contains(t, failure.Fields, "votes")
+	assert.Contains(t, failure.Fields, "source.isbn")
+
+	// Validation problems are listed by field
+	w = send("application/merge-patch+json", `{"author":null,"source":{"url":"nope"},"language":"???"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &failure)
+	assert.Contains(t, failure.Fields, "author")
+	assert.Contains(t, failure.Fields, "source.url")
+	assert.Contains(t, failure.Fields, "language")
+
+	w = send("application/merge-patch+json", `{"source":{"year":"last year"}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "source.year")
+
+	// JSON Patch, including a failing test operation
+	w = send("application/json-patch+json", `[{"op":"test","path":"/author","value":"someone"},{"op":"replace","path":"/author","value":"Someone Else"}]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&quote, quote.ID)
+	assert.Equal(t, "Someone Else", quote.Author)
+
+	w = send("application/json-patch+json", `[{"op":"test","path":"/author","value":"someone"}]`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = send("application/json-patch+json", `[{"op":"add","path":"/rating","value":3000}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = send("text/plain", `content=x`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Accept-Patch"))
+}
+
+func TestUpdateQuoteIgnoresServerFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	user := models.User{Username: "overwriter", Password: "hashed", Role: models.RoleModerator}
+	db.Create(&user)
+	quote := models.Quote{Content: "Original", Author: "someone"}
+	db.Create(&quote)
+
+	r := gin.Default()
+	r.PUT("/quotes/:id", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		UpdateQuote(c)
+	})
+
+	body := `{"id":999,"content":"Updated","rating":9000,"status":"rejected","created_at":"2000-01-01T00:00:00Z"}`
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/quotes/%d", quote.ID), bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated models.Quote
+	assert.NoError(t, db.First(&updated, quote.ID).Error)
+	assert.Equal(t, "Updated", updated.Content)
+	assert.Equal(t, "someone", updated.Author)
+	assert.Equal(t, 1500.0, updated.Rating)
+	assert.Equal(t, models.QuoteStatusApproved, updated.Status)
+	assert.Equal(t, quote.CreatedAt.Unix(), updated.CreatedAt.Unix())
+}
+
+func TestQuoteEditPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	owner := models.User{Username: "author", Password: "hashed"}
+	other := models.User{Username: "stranger", Password: "hashed"}
+	moderator := models.User{Username: "mod", Password: "hashed", Role: models.RoleModerator}
+	db.Create(&owner)
+	db.Create(&other)
+	db.Create(&moderator)
+	quote := models.Quote{Content: "Mine", Author: "someone", UserID: &owner.ID}
+	pending := models.Quote{Content: "Waiting", Author: "someone", UserID: &owner.ID, Status: models.QuoteStatusPending}
+	db.Create(&quote)
+	db.Create(&pending)
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Query("as"))
+		c.Set("user_id", uint(id))
+	})
+	r.PUT("/quotes/:id", UpdateQuote)
+	r.PATCH("/quotes/:id", PatchQuote)
+	r.DELETE("/quotes/:id", DeleteQuote)
+
+	send := func(method string, id, as uint, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, fmt.Sprintf("/quotes/%d?as=%d", id, as), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Other users can neither change nor delete the quote
+	assert.Equal(t, http.StatusForbidden, send("PUT", quote.ID, other.ID, `{"content":"Theirs"}`).Code)
+	assert.Equal(t, http.StatusForbidden, send("PATCH", quote.ID, other.ID, `{"content":"Theirs"}`).Code)
+	assert.Equal(t, http.StatusForbidden, send("DELETE", quote.ID, other.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, send("PUT", pending.ID, other.ID, `{"content":"Theirs"}`).Code, "hidden quotes stay hidden")
+	assert.Equal(t, http.StatusUnauthorized, send("PUT", quote.ID, 0, `{"content":"Theirs"}`).Code)
+	db.First(&quote, quote.ID)
+	assert.Equal(t, "Mine", quote.Content)
+
+	// Moderators can, and their edits stay approved
+	assert.Equal(t, http.StatusOK, send("PATCH", quote.ID, moderator.ID, `{"author":"Someone"}`).Code)
+	db.First(&quote, quote.ID)
+	assert.Equal(t, models.QuoteStatusApproved, quote.Status)
+
+	// The owner's edits go back to the moderation queue
+	assert.Equal(t, http.StatusOK, send("PUT", quote.ID, owner.ID, `{"content":"Still mine"}`).Code)
+	db.First(&quote, quote.ID)
+	assert.Equal(t, "Still mine", quote.Content)
+	assert.Equal(t, models.QuoteStatusPending, quote.Status)
+
+	assert.Equal(t, http.StatusOK, send("DELETE", pending.ID, owner.ID, "").Code)
+}
+
+func TestUpdateQuoteValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	user := models.User{Username: "blanker", Password: "hashed"}
+	db.Create(&user)
+	quote := models.Quote{Content: "Original", Author: "someone", UserID: &user.ID}
+	db.Create(&quote)
+
+	r := gin.Default()
+	r.PUT("/quotes/:id", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		UpdateQuote(c)
+	})
+	put := func(body string) (int, map[string]string) {
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/quotes/%d", quote.ID), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var failure struct {
+			Fields map[string]string `json:"fields"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &failure)
+		return w.Code, failure.Fields
+	}
+
+	// PUT checks the same fields as PATCH
+	code, fields := put(`{"content":"   ","author":""}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, map[string]string{"content": "is required", "author": "is required"}, fields)
+	code, fields = put(`{"author":"\t\n","source":{"url":"nope"}}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, fields, "author")
+	assert.Contains(t, fields, "source.url")
+
+	db.First(&quote, quote.ID)
+	assert.Equal(t, "Original", quote.Content)
+	assert.Equal(t, "someone", quote.Author)
+}
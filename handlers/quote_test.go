@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"Qoute-backend/config"
+	"Qoute-backend/middleware"
 	"Qoute-backend/models"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -56,3 +60,140 @@ func TestQuoteCRUDAndList(t *testing.T) {
 	assert.Contains(t, w2.Body.String(), "inspire")
 	assert.Contains(t, w2.Body.String(), "me")
 }
+
+func TestPublicQuoteReads(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	user := models.User{Username: "reader", Password: "hashed"}
+	db.Create(&user)
+	quote := models.Quote{Content: "public", Author: "anyone"}
+	db.Create(&quote)
+	db.Create(&models.Vote{QuoteID: quote.ID, UserID: &user.ID, VoterHash: ptr(config.VoterHash(user.ID))})
+	token, _ := middleware.GenerateJWT(user.ID)
+
+	r := gin.Default()
+	public := r.Group("/")
+	public.Use(middleware.OptionalAuthMiddleware())
+	{
+		public.GET("/quotes", GetQuotes)
+	}
+
+	// Anonymous visitors get the list without personal or voter fields
+	w1 := httptest.NewRecorder()
+	req1, _ := http.NewRequest("GET", "/quotes", nil)
+	r.ServeHTTP(w1, req1)
+	assert.Equal(t, http.StatusOK, w1.Code)
+	var anon []map[string]interface{}
+	json.Unmarshal(w1.Body.Bytes(), &anon)
+	assert.Len(t, anon, 1)
+	assert.EqualValues(t, 1, anon[0]["voteCount"])
+	assert.NotContains(t, anon[0], "votes")
+	assert.NotContains(t, anon[0], "has_voted")
+
+	// Signed in users see their vote state
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/quotes", nil)
+	req2.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w2, req2)
+	assert.Equal(t, http.StatusOK, w2.Code)
+	var authed []map[string]interface{}
+	json.Unmarshal(w2.Body.Bytes(), &authed)
+	assert.Equal(t, true, authed[0]["has_voted"])
+
+	// A bad token is rejected rather than treated as anonymous
+	w3 := httptest.NewRecorder()
+	req3, _ := http.NewRequest("GET", "/quotes", nil)
+	req3.Header.Set("Authorization", "Bearer not-a-token")
+	r.ServeHTTP(w3, req3)
+	assert.Equal(t, http.StatusUnauthorized, w3.Code)
+}
+
+func TestScheduledQuotes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	owner := models.User{Username: "scheduler", Password: "hashed"}
+	other := models.User{Username: "early_bird", Password: "hashed"}
+	db.Create(&owner)
+	db.Create(&other)
+	scheduled := models.Quote{Content: "Not yet", Author: "someone", UserID: &owner.ID, PublishAt: ptr(time.Now().Add(time.Hour))}
+	live := models.Quote{Content: "Already out", Author: "someone", UserID: &owner.ID}
+	db.Create(&scheduled)
+	db.Create(&live)
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		if as := c.Query("as"); as != "" {
+			id, _ := strconv.Atoi(as)
+			c.Set("user_id", uint(id))
+		}
+	})
+	r.GET("/quotes", GetQuotes)
+	r.GET("/quotes/:id", GetQuote)
+	r.PUT("/quotes/:id", UpdateQuote)
+	r.PATCH("/quotes/:id", PatchQuote)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	listed := func(query string) []uint {
+		var quotes []QuoteResponse
+		json.Unmarshal(get("/quotes?"+query).Body.Bytes(), &quotes)
+		return quoteIDs(quotes)
+	}
+	path := fmt.Sprintf("/quotes/%d", scheduled.ID)
+
+	// Only the owner sees the quote before it is published
+	assert.Equal(t, http.StatusNotFound, get(path).Code)
+	assert.Equal(t, http.StatusNotFound, get(fmt.Sprintf("%s?as=%d", path, other.ID)).Code)
+	assert.Equal(t, http.StatusOK, get(fmt.Sprintf("%s?as=%d", path, owner.ID)).Code)
+	assert.Equal(t, []uint{live.ID}, listed(""))
+	assert.Equal(t, []uint{live.ID}, listed(fmt.Sprintf("as=%d", other.ID)))
+	assert.ElementsMatch(t, []uint{live.ID, scheduled.ID}, listed(fmt.Sprintf("as=%d", owner.ID)))
+
+	// Other users cannot reschedule it, nor the owner's published quotes
+	reschedule := func(method string, id, as uint, publishAt time.Time) int {
+		body, _ := json.Marshal(gin.H{"publish_at": publishAt.UTC().Format(time.RFC3339)})
+		req, _ := http.NewRequest(method, fmt.Sprintf("/quotes/%d?as=%d", id, as), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusNotFound, reschedule("PUT", scheduled.ID, other.ID, time.Now().Add(-time.Minute)))
+	assert.Equal(t, http.StatusNotFound, reschedule("PATCH", scheduled.ID, other.ID, time.Now().Add(-time.Minute)))
+	assert.Equal(t, http.StatusForbidden, reschedule("PUT", live.ID, other.ID, time.Now().Add(time.Hour)))
+	assert.Equal(t, http.StatusForbidden, reschedule("PATCH", live.ID, other.ID, time.Now().Add(time.Hour)))
+	assert.Equal(t, http.StatusNotFound, get(path).Code)
+	assert.Equal(t, []uint{live.ID}, listed(""))
+
+	// The owner can reschedule it, and it shows up once the time has come
+	body, _ := json.Marshal(gin.H{"publish_at": time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)})
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("%s?as=%d", path, owner.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, get(path).Code)
+	assert.ElementsMatch(t, []uint{live.ID, scheduled.ID}, listed(""))
+
+	// Once published, the publication time is fixed
+	db.Model(&scheduled).Update("published_at", time.Now())
+	body, _ = json.Marshal(gin.H{"publish_at": time.Now().Add(time.Hour).Format(time.RFC3339)})
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("%s?as=%d", path, owner.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "publish_at")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PinDailyQuoteInput is the body used to pin a quote of the day
+type PinDailyQuoteInput struct {
+	QuoteID uint `json:"quote_id" binding:"required"`
+}
+
+// respondWithQuote loads a quote with its votes and sends it to the viewer
+func respondWithQuote(c *gin.Context, viewer quoteViewer, id uint, extra gin.H) {
+	var quote models.Quote
+	if err := config.DB.Scopes(viewer.visible, withResponseRelations).First(&quote, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	if extra == nil {
+		c.JSON(http.StatusOK, viewer.response(quote))
+		return
+	}
+	extra["quote"] = viewer.response(quote)
+	c.JSON(http.StatusOK, extra)
+}
+
+// GetRandomQuote returns a random quote matching the GetQuotes filters.
+// With weighted=true quotes are picked proportionally to their votes + 1.
+func GetRandomQuote(c *gin.Context) {
+	viewer, err := loadQuoteViewer(c, config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	filters, err := quoteFilters(c, viewer)
+	if err != nil {
+		respondWithFilterError(c, err)
+		return
+	}
+
+	if c.Query("weighted") == "true" {
+		var candidates []struct {
+			ID    uint
+			Votes int
+		}
+		db := config.DB.Model(&models.Quote{}).Scopes(viewer.visible, filters).
+			Select("quotes.id AS id, COUNT(votes.id) AS votes").
+			Joins("LEFT JOIN votes ON votes.quote_id = quotes.id").
+			Group("quotes.id")
+		if err := db.Scan(&candidates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(candidates) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No quotes found"})
+			return
+		}
+
+		total := 0
+		for _, candidate := range candidates {
+			total += candidate.Votes + 1
+		}
+		pick := rand.Intn(total)
+		for _, candidate := range candidates {
+			if pick -= candidate.Votes + 1; pick < 0 {
+				respondWithQuote(c, viewer, candidate.ID, nil)
+				return
+			}
+		}
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.Quote{}).Scopes(viewer.visible, filters).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No quotes found"})
+		return
+	}
+
+	var quote models.Quote
+	if err := config.DB.Scopes(viewer.visible, filters).Order("quotes.id").Offset(rand.Intn(int(count))).First(&quote).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondWithQuote(c, viewer, quote.ID, nil)
+}
+
+// pickDailyQuote deterministically picks a date's quote, so every instance
+// picks the same quote without sharing state. Each quote gets a score from
+// the date and its ID and the highest wins, so quotes joining or leaving
+// the candidates only change the pick when they are the pick.
+func pickDailyQuote(date string, quoteIDs []uint) uint {
+	var pick uint
+	var best uint64
+	for i, id := range quoteIDs {
+		sum := sha256.Sum256([]byte("daily:" + date + ":" + strconv.FormatUint(uint64(id), 10)))
+		if score := binary.BigEndian.Uint64(sum[:8]); i == 0 || score > best {
+			pick, best = id, score
+		}
+	}
+	return pick
+}
+
+// GetDailyQuote returns the quote of the day for the calendar day in the
+// timezone given by tz (an IANA name, UTC by default)
+func GetDailyQuote(c *gin.Context) {
+	location, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	viewer, err := loadQuoteViewer(c, config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now().In(location)
+	date := now.Format(time.DateOnly)
+	response := gin.H{"date": date, "timezone": location.String(), "pinned": false}
+
+	// An admin pin wins over the computed pick
+	var pin models.DailyQuote
+	err = config.DB.Where("date = ?", date).First(&pin).Error
+	if err == nil {
+		var pinned models.Quote
+		if config.DB.Scopes(publiclyVisible).First(&pinned, pin.QuoteID).Error == nil {
+			response["pinned"] = true
+			respondWithQuote(c, viewer, pin.QuoteID, response)
+			return
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only public quotes that existed when the day started are candidates,
+	// so quotes added or published during the day do not change today's pick
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	var quoteIDs []uint
+	if err := config.DB.Model(&models.Quote{}).Scopes(publiclyVisible).Where("COALESCE(quotes.publish_at, quotes.created_at) < ?", dayStart.In(time.Local)).Pluck("id", &quoteIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(quoteIDs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No quotes found"})
+		return
+	}
+
+	respondWithQuote(c, viewer, pickDailyQuote(date, quoteIDs), response)
+}
+
+// PinDailyQuote pins a quote as the quote of the day for a date
+func PinDailyQuote(c *gin.Context) {
+	date := c.Param("date")
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date must be formatted as YYYY-MM-DD"})
+		return
+	}
+
+	var input PinDailyQuoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var quote models.Quote
+	if err := config.DB.First(&quote, input.QuoteID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	pin := models.DailyQuote{Date: date}
+	if err := config.DB.Where(pin).Assign(models.DailyQuote{QuoteID: quote.ID}).FirstOrCreate(&pin).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pin)
+}
+
+// UnpinDailyQuote removes the pinned quote of the day for a date
+func UnpinDailyQuote(c *gin.Context) {
+	result := config.DB.Where("date = ?", c.Param("date")).Delete(&models.DailyQuote{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpin quote"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pinned quote for this date"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quote unpinned successfully"})
+}
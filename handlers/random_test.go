@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/middleware"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomQuoteHonoursFilters(t *testing.T) {
+	router, _ := setupTestRouterWithQuotes(t)
+	router.GET("/quotes/random", GetRandomQuote)
+
+	for _, query := range []string{"?author=Socrates", "?author=Socrates&weighted=true"} {
+		for i := 0; i < 5; i++ {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quotes/random"+query, nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var quote QuoteResponse
+			json.Unmarshal(w.Body.Bytes(), &quote)
+			assert.Equal(t, "Socrates", quote.Author)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/quotes/random?author=Nobody", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDailyQuote(t *testing.T) {
+	router, quotes := setupTestRouterWithQuotes(t)
+	db := config.DB
+	// Quotes are candidates from the day after they were added
+	db.Model(&models.Quote{}).Where("1 = 1").UpdateColumn("created_at", time.Now().AddDate(0, 0, -2))
+
+	admin := models.User{Username: "daily_admin", Password: "password", Role: models.RoleAdmin}
+	db.Create(&admin)
+	user := models.User{Username: "daily_user", Password: "password"}
+	db.Create(&user)
+	adminToken, _ := middleware.GenerateJWT(admin.ID)
+	userToken, _ := middleware.GenerateJWT(user.ID)
+
+	router.GET("/quotes/daily", GetDailyQuote)
+	admins := router.Group("/")
+	admins.Use(middleware.AuthMiddleware(), middleware.RequireRole(db, models.RoleAdmin))
+	admins.PUT("/quotes/daily/:date", PinDailyQuote)
+
+	getDaily := func() map[string]interface{} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/quotes/daily?tz=Asia/Bangkok", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	// The pick is stable between calls
+	first := getDaily()
+	assert.Equal(t, first["quote"], getDaily()["quote"])
+	assert.Equal(t, false, first["pinned"])
+	assert.Equal(t, "Asia/Bangkok", first["timezone"])
+
+	// Quotes added today or hidden during the day don't move the pick
+	pick := uint(first["quote"].(map[string]interface{})["id"].(float64))
+	db.Create(&models.Quote{Content: "Added today", Author: "Newcomer"})
+	for _, quote := range quotes {
+		if quote.ID != pick {
+			db.Model(&quote).Update("hidden", true)
+			break
+		}
+	}
+	assert.Equal(t, first["quote"].(map[string]interface{})["id"], getDaily()["quote"].(map[string]interface{})["id"])
+
+	// Only admins can pin
+	bangkok, _ := time.LoadLocation("Asia/Bangkok")
+	today := time.Now().In(bangkok).Format(time.DateOnly)
+	pinned := quotes[3]
+	body, _ := json.Marshal(PinDailyQuoteInput{QuoteID: pinned.ID})
+
+	w1 := httptest.NewRecorder()
+	req1, _ := http.NewRequest("PUT", "/quotes/daily/"+today, bytes.NewBuffer(body))
+	req1.Header.Set("Authorization", "Bearer "+userToken)
+	router.ServeHTTP(w1, req1)
+	assert.Equal(t, http.StatusForbidden, w1.Code)
+
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("PUT", "/quotes/daily/"+today, bytes.NewBuffer(body))
+	req2.Header.Set("Authorization", "Bearer "+adminToken)
+	router.ServeHTTP(w2, req2)
+	assert.Equal(t, http.StatusOK, w2.Code)
+
+	resp := getDaily()
+	assert.Equal(t, true, resp["pinned"])
+	assert.EqualValues(t, pinned.ID, resp["quote"].(map[string]interface{})["id"])
+}
+
+func TestPickDailyQuote(t *testing.T) {
+	ids := []uint{3, 5, 8, 13, 21, 34, 55}
+	pick := pickDailyQuote("2025-01-01", ids)
+	assert.Contains(t, ids, pick)
+	assert.Equal(t, pick, pickDailyQuote("2025-01-01", []uint{55, 34, 21, 13, 8, 5, 3}), "order does not matter")
+
+	// Dropping any other quote keeps the pick
+	for i, id := range ids {
+		if id == pick {
+			continue
+		}
+		others := append(append([]uint{}, ids[:i]...), ids[i+1:]...)
+		assert.Equal(t, pick, pickDailyQuote("2025-01-01", others))
+	}
+
+	// Different days spread over the quotes
+	picks := map[uint]bool{}
+	for day := 1; day <= 28; day++ {
+		picks[pickDailyQuote(time.Date(2025, 2, day, 0, 0, 0, 0, time.UTC).Format(time.DateOnly), ids)] = true
+	}
+	assert.Greater(t, len(picks), 1)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// VoteReceipt is a signed proof that a vote was recorded
+type VoteReceipt struct {
+	VoteID    uint   `json:"vote_id" binding:"required"`
+	QuoteID   uint   `json:"quote_id" binding:"required"`
+	UserHash  string `json:"user_hash" binding:"required"`
+	Timestamp int64  `json:"timestamp" binding:"required"`
+	KeyID     string `json:"key_id" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+// payload returns the canonical string covered by the receipt signature
+func (r VoteReceipt) payload() string {
+	return fmt.Sprintf("v1|%s|%d|%d|%s|%d", r.KeyID, r.VoteID, r.QuoteID, r.UserHash, r.Timestamp)
+}
+
+// receiptUserHash derives the receipt's user hash from the stored voter
+// hash, so receipts verify in every vote privacy mode
+func receiptUserHash(key config.ReceiptKey, vote models.Vote) string {
+	mac := hmac.New(sha256.New, key.Secret)
+	if vote.VoterHash != nil {
+		mac.Write([]byte(*vote.VoterHash))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func signReceipt(key config.ReceiptKey, r VoteReceipt) string {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(r.payload()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issueReceipt builds a receipt for a vote signed with the active key
+func issueReceipt(vote models.Vote) VoteReceipt {
+	key := config.ReceiptKeys()[0]
+	receipt := VoteReceipt{
+		VoteID:    vote.ID,
+		QuoteID:   vote.QuoteID,
+		UserHash:  receiptUserHash(key, vote),
+		Timestamp: vote.CreatedAt.Unix(),
+		KeyID:     key.ID,
+	}
+	receipt.Signature = signReceipt(key, receipt)
+	return receipt
+}
+
+// VerifyReceipt checks a vote receipt against the server keys and the database
+func (h *VoteHandler) VerifyReceipt(c *gin.Context) {
+	var receipt VoteReceipt
+	if err := c.ShouldBindJSON(&receipt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Find the key the receipt claims to be signed with
+	var key *config.ReceiptKey
+	for _, k := range config.ReceiptKeys() {
+		if k.ID == receipt.KeyID {
+			key = &k
+			break
+		}
+	}
+	if key == nil {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "reason": "Unknown signing key"})
+		return
+	}
+
+	if !hmac.Equal([]byte(signReceipt(*key, receipt)), []byte(receipt.Signature)) {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "reason": "Invalid signature"})
+		return
+	}
+
+	// A genuine signature is not enough: the vote must still be on record
+	var vote models.Vote
+	if err := h.db.First(&vote, receipt.VoteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusOK, gin.H{"valid": false, "reason": "Vote no longer exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify receipt"})
+		return
+	}
+
+	if vote.QuoteID != receipt.QuoteID ||
+		vote.CreatedAt.Unix() != receipt.Timestamp ||
+		receiptUserHash(*key, vote) != receipt.UserHash {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "reason": "Receipt does not match recorded vote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":    true,
+		"vote_id":  vote.ID,
+		"quote_id": vote.QuoteID,
+	})
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func verifyReceipt(r *gin.Engine, receipt VoteReceipt) map[string]interface{} {
+	body, _ := json.Marshal(receipt)
+	req, _ := http.NewRequest("POST", "/votes/verify", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp
+}
+
+func TestVoteReceiptVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	os.Setenv("RECEIPT_KEYS", "k1:first-secret")
+	defer os.Unsetenv("RECEIPT_KEYS")
+
+	user := models.User{Username: "receiptuser", Password: "hashed"}
+	db.Create(&user)
+	quote := models.Quote{Content: "receipt", Author: "receiptauthor"}
+	db.Create(&quote)
+
+	r := gin.Default()
+	voteHandler := NewVoteHandler(db)
+	r.POST("/quotes/:id/vote", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		voteHandler.CreateVote(c)
+	})
+	r.POST("/votes/verify", voteHandler.VerifyReceipt)
+
+	req, _ := http.NewRequest("POST", "/quotes/1/vote", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp struct {
+		Receipt VoteReceipt `json:"receipt"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	receipt := resp.Receipt
+	assert.Equal(t, "k1", receipt.KeyID)
+	assert.NotEmpty(t, receipt.Signature)
+
+	assert.Equal(t, true, verifyReceipt(r, receipt)["valid"])
+
+	// Tampering with any field breaks the signature
+	tampered := receipt
+	tampered.QuoteID = 42
+	assert.Equal(t, false, verifyReceipt(r, tampered)["valid"])
+
+	// After rotation the old key still verifies receipts it signed
+	os.Setenv("RECEIPT_KEYS", "k2:second-secret,k1:first-secret")
+	assert.Equal(t, true, verifyReceipt(r, receipt)["valid"])
+
+	// Retired keys no longer verify
+	os.Setenv("RECEIPT_KEYS", "k2:second-secret")
+	assert.Equal(t, false, verifyReceipt(r, receipt)["valid"])
+
+	// Receipts for removed votes are rejected
+	os.Setenv("RECEIPT_KEYS", "k1:first-secret")
+	db.Delete(&models.Vote{}, receipt.VoteID)
+	result := verifyReceipt(r, receipt)
+	assert.Equal(t, false, result["valid"])
+	assert.Equal(t, "Vote no longer exists", result["reason"])
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"Qoute-backend/models"
+	"Qoute-backend/related"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Related quote limits
+const (
+	defaultRelatedLimit = 10
+	maxRelatedLimit     = 50
+	relatedIndexBatch   = 500
+
+	// relatedRefreshInterval is how often the index is checked for changed
+	// quotes. Edits can take this long to show up in related quotes.
+	relatedRefreshInterval = 30 * time.Second
+)
+
+// likesSQL lists which user liked which quote: votes of signed in users,
+// quotes saved to collections and battle picks. Anonymous votes have no user
+// and are left out.
+const likesSQL = `SELECT user_id, quote_id FROM votes WHERE user_id IS NOT NULL
+	UNION SELECT collections.user_id, collection_items.quote_id FROM collection_items
+		JOIN collections ON collections.id = collection_items.collection_id
+	UNION SELECT user_id, winner_id FROM battles WHERE winner_id IS NOT NULL`
+
+// quoteLike is a user liking a quote
+type quoteLike struct {
+	UserID  uint
+	QuoteID uint
+}
+
+// quoteLikes returns the likes whose column, user_id or quote_id, is one of
+// values
+func quoteLikes(db *gorm.DB, column string, values []uint) ([]quoteLike, error) {
+	var likes []quoteLike
+	if len(values) == 0 {
+		return likes, nil
+	}
+	err := db.Raw("SELECT user_id, quote_id FROM ("+likesSQL+") AS likes WHERE likes."+column+" IN ?", values).Scan(&likes).Error
+	return likes, err
+}
+
+// RelatedHandler serves related quotes from an in-memory text index of all
+// quotes. The feed shares it to score quotes by similar wording.
+type RelatedHandler struct {
+	db          *gorm.DB
+	index       *related.Index
+	refresh     sync.Mutex       // Serializes index refreshes
+	refreshedAt time.Time        // When the index was last brought up to date
+	now         func() time.Time // Clock for throttling refreshes
+}
+
+// NewRelatedHandler returns a handler with an empty index. The index is
+// filled on the first request.
+func NewRelatedHandler(db *gorm.DB) *RelatedHandler {
+	return &RelatedHandler{db: db, index: related.NewIndex(), now: time.Now}
+}
+
+// RelatedQuote is a related quote with why it was picked
+type RelatedQuote struct {
+	QuoteResponse
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// refreshIndex brings the index up to date, at most once every
+// relatedRefreshInterval. Only quotes whose version changed since they were
+// indexed are read again, and deleted ones dropped.
+func (h *RelatedHandler) refreshIndex() error {
+	h.refresh.Lock()
+	defer h.refresh.Unlock()
+
+	now := h.now()
+	if !h.refreshedAt.IsZero() && now.Sub(h.refreshedAt) < relatedRefreshInterval {
+		return nil
+	}
+
+	var rows []struct {
+		ID      uint
+		Version uint
+	}
+	if err := h.db.Model(&models.Quote{}).Select("id", "version").Scan(&rows).Error; err != nil {
+		return err
+	}
+	versions := make(map[uint]uint, len(rows))
+	for _, row := range rows {
+		versions[row.ID] = row.Version
+	}
+
+	stale := h.index.Sync(versions)
+	for start := 0; start < len(stale); start += relatedIndexBatch {
+		end := min(start+relatedIndexBatch, len(stale))
+		var quotes []models.Quote
+		if err := h.db.Select("id", "version", "content", "author", "language").Where("id IN ?", stale[start:end]).Find(&quotes).Error; err != nil {
+			return err
+		}
+		for _, quote := range quotes {
+			h.index.Add(related.Document{ID: quote.ID, Version: quote.Version, Text: quote.Content, Author: quote.Author, Language: quote.Language})
+		}
+	}
+	h.refreshedAt = now
+	return nil
+}
+
+// coLikes counts, for every other quote, the users who liked both it and
+// the given quote
+func (h *RelatedHandler) coLikes(quoteID uint) (map[uint]int, error) {
+	likers, err := quoteLikes(h.db, "quote_id", []uint{quoteID})
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uint, len(likers))
+	for i, like := range likers {
+		userIDs[i] = like.UserID
+	}
+	likes, err := quoteLikes(h.db, "user_id", userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[uint]int{}
+	for _, like := range likes {
+		if like.QuoteID != quoteID {
+			counts[like.QuoteID]++ // The union has one row per user and quote
+		}
+	}
+	return counts, nil
+}
+
+// GetRelated lists quotes related to a quote, best first, with a score and
+// the reasons for each. Quotes are related by similar wording, a shared
+// author and users liking both.
+func (h *RelatedHandler) GetRelated(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultRelatedLimit)))
+	if err != nil || limit < 1 || limit > maxRelatedLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 50"})
+		return
+	}
+
+	viewer, err := loadQuoteViewer(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var quote models.Quote
+	if err := h.db.Scopes(viewer.visible).First(&quote, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	if err := h.refreshIndex(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index quotes"})
+		return
+	}
+	coLikes, err := h.coLikes(quote.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	signals := map[uint]related.Signals{}
+	for id, similarity := range h.index.Similar(quote.ID) {
+		signals[id] = related.Signals{Similarity: similarity}
+	}
+	for id, count := range coLikes {
+		s := signals[id]
+		s.CoLikes = count
+		signals[id] = s
+	}
+
+	// Rank every candidate, then load the best ones the viewer may see
+	type candidate struct {
+		id    uint
+		score float64
+	}
+	var candidates []candidate
+	for id, s := range signals {
+		if score := s.Score(); score >= related.MinScore {
+			candidates = append(candidates, candidate{id, score})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].id < candidates[j].id
+	})
+	if len(candidates) > maxRelatedLimit*4 {
+		candidates = candidates[:maxRelatedLimit*4]
+	}
+	ids := make([]uint, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.id
+	}
+
+	var quotes []models.Quote
+	if err := h.db.Scopes(viewer.visible, withResponseRelations).Where("quotes.id IN ?", ids).Find(&quotes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byID := make(map[uint]models.Quote, len(quotes))
+	for _, q := range quotes {
+		byID[q.ID] = q
+	}
+
+	response := []RelatedQuote{}
+	for _, candidate := range candidates {
+		q, ok := byID[candidate.id]
+		if !ok {
+			continue
+		}
+		response = append(response, RelatedQuote{
+			QuoteResponse: viewer.response(q),
+			Score:         math.Round(candidate.score*1000) / 1000,
+			Reasons:       signals[candidate.id].Reasons(),
+		})
+		if len(response) == limit {
+			break
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
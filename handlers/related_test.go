@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelatedQuotes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	users := []models.User{{Username: "fan", Password: "x"}, {Username: "critic", Password: "x"}}
+	db.Create(&users)
+	quotes := []models.Quote{
+		{Content: "The only true wisdom is in knowing you know nothing.", Author: "Socrates", Language: "en"},
+		{Content: "Knowing yourself is the beginning of all wisdom.", Author: "Aristotle", Language: "en"},
+		{Content: "An unexamined life is not worth living.", Author: "Socrates", Language: "en"},
+		{Content: "Be the change that you wish to see in the world.", Author: "Mahatma Gandhi", Language: "en"},
+		{Content: "Stay hungry, stay foolish.", Author: "Steve Jobs", Language: "en"},
+		{Content: "True wisdom comes to each of us when we realize how little we know.", Author: "Socrates", Language: "en", Status: models.QuoteStatusPending},
+	}
+	db.Create(&quotes)
+
+	// Both users liked the first quote, and one of them also saved the fifth
+	// while the other picked it in a battle
+	db.Create(&models.Vote{QuoteID: quotes[0].ID, UserID: &users[0].ID})
+	collection := models.Collection{UserID: users[0].ID, Title: "Favourites"}
+	db.Create(&collection)
+	db.Create(&models.CollectionItem{CollectionID: collection.ID, QuoteID: quotes[4].ID})
+	db.Create(&models.Battle{UserID: users[1].ID, QuoteAID: quotes[0].ID, QuoteBID: quotes[1].ID, WinnerID: &quotes[0].ID})
+	db.Create(&models.Battle{UserID: users[1].ID, QuoteAID: quotes[3].ID, QuoteBID: quotes[4].ID, WinnerID: &quotes[4].ID})
+
+	h := NewRelatedHandler(db)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	r := gin.Default()
+	r.GET("/quotes/:id/related", h.GetRelated)
+
+	get := func(path string) ([]RelatedQuote, int) {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var response []RelatedQuote
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response, w.Code
+	}
+
+	related, code := get(fmt.Sprintf("/quotes/%d/related", quotes[0].ID))
+	assert.Equal(t, http.StatusOK, code)
+	ids := make([]uint, len(related))
+	for i, quote := range related {
+		ids[i] = quote.ID
+		assert.NotEmpty(t, quote.Reasons)
+		assert.Greater(t, quote.Score, 0.0)
+	}
+	// The pending quote is not visible and the unrelated quote is left out
+	assert.Equal(t, []uint{quotes[2].ID, quotes[1].ID, quotes[4].ID}, ids)
+	assert.Equal(t, []string{"Same author"}, related[0].Reasons)
+	assert.Equal(t, []string{"Similar wording: knowing, wisdom"}, related[1].Reasons)
+	assert.Equal(t, []string{"Liked by people who liked this quote"}, related[2].Reasons)
+
+	related, _ = get(fmt.Sprintf("/quotes/%d/related?limit=1", quotes[0].ID))
+	assert.Len(t, related, 1)
+
+	// Edits are picked up by the index once the refresh interval has passed
+	db.Model(&quotes[3]).Updates(map[string]interface{}{"content": "Wisdom is knowing what to do next.", "version": bumpVersion})
+	hasEdited := func() bool {
+		related, _ := get(fmt.Sprintf("/quotes/%d/related", quotes[0].ID))
+		for _, quote := range related {
+			if quote.ID == quotes[3].ID {
+				return true
+			}
+		}
+		return false
+	}
+	assert.False(t, hasEdited())
+	now = now.Add(relatedRefreshInterval)
+	assert.True(t, hasEdited())
+
+	// Deleted quotes drop out
+	db.Delete(&quotes[1])
+	now = now.Add(relatedRefreshInterval)
+	related, _ = get(fmt.Sprintf("/quotes/%d/related", quotes[0].ID))
+	for _, quote := range related {
+		assert.NotEqual(t, quotes[1].ID, quote.ID)
+	}
+	assert.Equal(t, 5, h.index.Len())
+
+	_, code = get(fmt.Sprintf("/quotes/%d/related?limit=0", quotes[0].ID))
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = get(fmt.Sprintf("/quotes/%d/related", quotes[5].ID))
+	assert.Equal(t, http.StatusNotFound, code)
+}
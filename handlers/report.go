@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Report resolution actions
+const (
+	// ReportActionDismiss closes the report and unhides the quote when no
+	// other reports are open
+	ReportActionDismiss = "dismiss"
+	// ReportActionHide keeps the quote hidden and resolves its open reports
+	ReportActionHide = "hide"
+	// ReportActionRemove moves the quote to the trash, deletes its votes so
+	// their voters can vote again, and resolves its open reports
+	ReportActionRemove = "remove"
+)
+
+// errReportClosed is returned when resolving a report that is not open
+var errReportClosed = errors.New("report is not open")
+
+var reportReasons = []string{
+	models.ReportReasonMisattributed,
+	models.ReportReasonOffensive,
+	models.ReportReasonSpam,
+	models.ReportReasonDuplicate,
+	models.ReportReasonOther,
+}
+
+type ReportHandler struct {
+	db *gorm.DB
+}
+
+func NewReportHandler(db *gorm.DB) *ReportHandler {
+	return &ReportHandler{db: db}
+}
+
+// CreateReportInput is the body of a quote report
+type CreateReportInput struct {
+	Reason  string `json:"reason" binding:"required"`
+	Details string `json:"details" binding:"max=1000"`
+}
+
+// ResolveReportInput is the body of a moderator's report decision
+type ResolveReportInput struct {
+	Action string `json:"action" binding:"required"`
+	Note   string `json:"note" binding:"max=1000"`
+}
+
+// CreateReport flags a quote for moderators
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	quoteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quote ID"})
+		return
+	}
+
+	var input CreateReportInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !slices.Contains(reportReasons, input.Reason) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reason", "reasons": reportReasons})
+		return
+	}
+	if input.Reason == models.ReportReasonOther && input.Details == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Details are required for reason other"})
+		return
+	}
+
+	// Start a transaction
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var recent int64
+	if err := tx.Model(&models.Report{}).Where("user_id = ? AND created_at > ?", userID, time.Now().Add(-time.Hour)).Count(&recent).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report"})
+		return
+	}
+	if recent >= int64(config.ReportRateLimit()) {
+		tx.Rollback()
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many reports, please try again later"})
+		return
+	}
+
+	var quote models.Quote
+	if err := tx.Scopes(publiclyVisible).First(&quote, quoteID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	var existing models.Report
+	if err := tx.Where("quote_id = ? AND user_id = ?", quoteID, userID).First(&existing).Error; err == nil {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reported this quote"})
+		return
+	}
+
+	report := models.Report{
+		QuoteID: quote.ID,
+		UserID:  userID.(uint),
+		Reason:  input.Reason,
+		Details: input.Details,
+		Status:  models.ReportStatusOpen,
+	}
+	if err := tx.Create(&report).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report"})
+		return
+	}
+
+	// Hide the quote once enough users have reported it
+	if threshold := config.ReportHideThreshold(); threshold > 0 {
+		var open int64
+		if err := tx.Model(&models.Report{}).Where("quote_id = ? AND status = ?", quote.ID, models.ReportStatusOpen).Count(&open).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report"})
+			return
+		}
+		if open >= int64(threshold) {
+			if err := tx.Model(&quote).Updates(map[string]interface{}{"hidden": true, "version": bumpVersion}).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hide quote"})
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process report"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// GetReports lists reports for moderators, oldest first
+func (h *ReportHandler) GetReports(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReportStatusOpen)
+	if status != models.ReportStatusOpen && status != models.ReportStatusResolved && status != models.ReportStatusDismissed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, resolved or dismissed"})
+		return
+	}
+
+	db := h.db.Where("status = ?", status)
+	if quoteID := c.Query("quote_id"); quoteID != "" {
+		db = db.Where("quote_id = ?", quoteID)
+	}
+
+	var reports []models.Report
+	if err := db.Preload("Quote", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).Order("created_at asc").Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// ResolveReport records a moderator's decision on a report
+func (h *ReportHandler) ResolveReport(c *gin.Context) {
+	reportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	var input ResolveReportInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Action != ReportActionDismiss && input.Action != ReportActionHide && input.Action != ReportActionRemove {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be dismiss, hide or remove"})
+		return
+	}
+
+	var report models.Report
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&report, reportID).Error; err != nil {
+			return err
+		}
+		if report.Status != models.ReportStatusOpen {
+			return errReportClosed
+		}
+
+		moderatorID := c.GetUint("user_id")
+		now := time.Now()
+		resolution := map[string]interface{}{
+			"status":      models.ReportStatusResolved,
+			"resolution":  input.Action,
+			"resolved_by": moderatorID,
+			"resolved_at": now,
+		}
+		if input.Note != "" {
+			resolution["resolution"] = input.Action + ": " + input.Note
+		}
+
+		if input.Action == ReportActionDismiss {
+			resolution["status"] = models.ReportStatusDismissed
+			result := tx.Model(&report).Where("status = ?", models.ReportStatusOpen).Updates(resolution)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errReportClosed
+			}
+
+			// Unhide the quote when nothing else is holding it back
+			var open int64
+			if err := tx.Model(&models.Report{}).Where("quote_id = ? AND status = ?", report.QuoteID, models.ReportStatusOpen).Count(&open).Error; err != nil {
+				return err
+			}
+			if open == 0 {
+				return tx.Model(&models.Quote{}).Where("id = ?", report.QuoteID).Updates(map[string]interface{}{"hidden": false, "version": bumpVersion}).Error
+			}
+			return nil
+		}
+
+		// Hiding or removing settles every open report on the quote
+		result := tx.Model(&models.Report{}).Where("quote_id = ? AND status = ?", report.QuoteID, models.ReportStatusOpen).Updates(resolution)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errReportClosed
+		}
+		if input.Action == ReportActionHide {
+			return tx.Model(&models.Quote{}).Where("id = ?", report.QuoteID).Updates(map[string]interface{}{"hidden": true, "version": bumpVersion}).Error
+		}
+		// Votes would otherwise wait for the trash purge, and keep their
+		// voters from voting again meanwhile
+		if err := tx.Where("quote_id = ?", report.QuoteID).Delete(&models.Vote{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Quote{}, report.QuoteID).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+			return
+		}
+		if errors.Is(err, errReportClosed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Report was already resolved", "status": report.Status})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve report"})
+		return
+	}
+
+	if err := h.db.First(&report, report.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
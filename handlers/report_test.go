@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportsHideAndResolve(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	os.Setenv("REPORT_HIDE_THRESHOLD", "2")
+	defer os.Unsetenv("REPORT_HIDE_THRESHOLD")
+
+	quote := models.Quote{Content: "contested", Author: "unknown"}
+	db.Create(&quote)
+	reporters := []models.User{{Username: "reporter1", Password: "x"}, {Username: "reporter2", Password: "x"}}
+	db.Create(&reporters)
+	moderator := models.User{Username: "report_mod", Password: "x", Role: models.RoleModerator}
+	db.Create(&moderator)
+
+	r := gin.Default()
+	reportHandler := NewReportHandler(db)
+	r.POST("/quotes/:id/report/:user", func(c *gin.Context) {
+		userID, _ := strconv.ParseUint(c.Param("user"), 10, 32)
+		c.Set("user_id", uint(userID))
+		reportHandler.CreateReport(c)
+	})
+	r.POST("/moderation/reports/:id/resolve", func(c *gin.Context) {
+		c.Set("user_id", moderator.ID)
+		reportHandler.ResolveReport(c)
+	})
+	r.GET("/moderation/reports", reportHandler.GetReports)
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	reportPath := func(user models.User) string {
+		return fmt.Sprintf("/quotes/%d/report/%d", quote.ID, user.ID)
+	}
+
+	// Reasons are validated
+	w := post(reportPath(reporters[0]), CreateReportInput{Reason: "boring"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post(reportPath(reporters[0]), CreateReportInput{Reason: models.ReportReasonMisattributed})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var report models.Report
+	json.Unmarshal(w.Body.Bytes(), &report)
+
+	// One report per user and quote
+	w = post(reportPath(reporters[0]), CreateReportInput{Reason: models.ReportReasonSpam})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Reaching the threshold hides the quote
+	w = post(reportPath(reporters[1]), CreateReportInput{Reason: models.ReportReasonOffensive})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	db.First(&quote, quote.ID)
+	assert.True(t, quote.Hidden)
+
+	req, _ := http.NewRequest("GET", "/moderation/reports", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var open []models.Report
+	json.Unmarshal(w.Body.Bytes(), &open)
+	assert.Len(t, open, 2)
+	assert.Equal(t, "contested", open[0].Quote.Content)
+
+	// Dismissing one report keeps the quote hidden while another is open
+	w = post(fmt.Sprintf("/moderation/reports/%d/resolve", report.ID), ResolveReportInput{Action: ReportActionDismiss})
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&quote, quote.ID)
+	assert.True(t, quote.Hidden)
+
+	// Dismissing the last one unhides it
+	w = post(fmt.Sprintf("/moderation/reports/%d/resolve", open[1].ID), ResolveReportInput{Action: ReportActionDismiss, Note: "Attribution checked"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &report)
+	assert.Equal(t, models.ReportStatusDismissed, report.Status)
+	assert.Equal(t, "dismiss: Attribution checked", report.Resolution)
+	db.First(&quote, quote.ID)
+	assert.False(t, quote.Hidden)
+
+	// Resolved reports cannot be resolved again
+	for _, action := range []string{ReportActionDismiss, ReportActionHide, ReportActionRemove} {
+		w = post(fmt.Sprintf("/moderation/reports/%d/resolve", report.ID), ResolveReportInput{Action: action})
+		assert.Equal(t, http.StatusConflict, w.Code, action)
+	}
+	assert.NoError(t, db.First(&quote, quote.ID).Error)
+	assert.False(t, quote.Hidden)
+	db.First(&report, report.ID)
+	assert.Equal(t, "dismiss: Attribution checked", report.Resolution)
+}
+
+func TestRemoveReportedQuoteDeletesVotes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	voter := models.User{Username: "voter", Password: "x"}
+	moderator := models.User{Username: "report_mod", Password: "x", Role: models.RoleModerator}
+	db.Create(&voter)
+	db.Create(&moderator)
+	quotes := []models.Quote{{Content: "removed", Author: "a"}, {Content: "kept", Author: "b"}}
+	db.Create(&quotes)
+	db.Create(&models.Vote{QuoteID: quotes[0].ID, UserID: &voter.ID, VoterHash: ptr(config.VoterHash(voter.ID))})
+	report := models.Report{QuoteID: quotes[0].ID, UserID: voter.ID, Reason: models.ReportReasonSpam, Status: models.ReportStatusOpen}
+	db.Create(&report)
+
+	r := gin.Default()
+	r.POST("/moderation/reports/:id/resolve", func(c *gin.Context) {
+		c.Set("user_id", moderator.ID)
+		NewReportHandler(db).ResolveReport(c)
+	})
+	r.POST("/quotes/:id/vote", func(c *gin.Context) {
+		c.Set("user_id", voter.ID)
+		NewVoteHandler(db).CreateVote(c)
+	})
+	post := func(path string, body interface{}) int {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, post(fmt.Sprintf("/moderation/reports/%d/resolve", report.ID), ResolveReportInput{Action: ReportActionRemove}))
+	assert.Error(t, db.First(&models.Quote{}, quotes[0].ID).Error, "the quote is in the trash")
+	var votes int64
+	db.Model(&models.Vote{}).Where("quote_id = ?", quotes[0].ID).Count(&votes)
+	assert.Zero(t, votes)
+
+	// The voter is free to vote again
+	assert.Equal(t, http.StatusCreated, post(fmt.Sprintf("/quotes/%d/vote", quotes[1].ID), nil))
+}
+
+func TestReportRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	os.Setenv("REPORT_RATE_LIMIT", "1")
+	defer os.Unsetenv("REPORT_RATE_LIMIT")
+
+	user := models.User{Username: "eager_reporter", Password: "x"}
+	db.Create(&user)
+	quotes := []models.Quote{{Content: "one", Author: "a"}, {Content: "two", Author: "a"}}
+	db.Create(&quotes)
+
+	r := gin.Default()
+	reportHandler := NewReportHandler(db)
+	r.POST("/quotes/:id/report", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		reportHandler.CreateReport(c)
+	})
+
+	codes := []int{}
+	for _, quote := range quotes {
+		payload, _ := json.Marshal(CreateReportInput{Reason: models.ReportReasonSpam})
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/quotes/%d/report", quote.ID), bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{http.StatusCreated, http.StatusTooManyRequests}, codes)
+}
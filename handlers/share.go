@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"Qoute-backend/card"
+	"Qoute-backend/config"
+	"Qoute-backend/langdetect"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// shareTemplates are the HTML share page, its not found page and the
+// snippet embedded through oEmbed
+var shareTemplates = template.Must(template.ParseFS(templateFiles, "templates/*.html"))
+
+const (
+	siteName               = "Quotes"
+	shareDescriptionLength = 200 // Runes of a quote shown in link previews
+	defaultEmbedWidth      = 550
+	embedCharWidth         = 9   // Average pixel width of a character in the embed
+	embedLineHeight        = 24  // Pixel height of a line of the embed
+	embedChromeHeight      = 72  // Pixel height of the attribution line and margins
+	shareMaxAge            = 300 // Seconds share pages and oEmbed responses may be cached for
+	oEmbedCacheAge         = 3600
+)
+
+// sharePath matches the path of a share page and captures the quote ID
+var sharePath = regexp.MustCompile(`^/q/([0-9]+)/?$`)
+
+// sharePage is what the share page and embed templates show
+type sharePage struct {
+	Quote       models.Quote
+	SiteName    string
+	Lang        string
+	Title       string
+	Description string
+	URL         string
+	OEmbedURL   string
+	ImageURL    string
+	ImageWidth  int
+	ImageHeight int
+	JSONLD      map[string]interface{}
+}
+
+// oEmbedResponse is a rich oEmbed response, as in https://oembed.com
+type oEmbedResponse struct {
+	Version         string `json:"version"`
+	Type            string `json:"type"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	CacheAge        int    `json:"cache_age"`
+	ThumbnailURL    string `json:"thumbnail_url"`
+	ThumbnailWidth  int    `json:"thumbnail_width"`
+	ThumbnailHeight int    `json:"thumbnail_height"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+}
+
+// embedHeight estimates the pixel height of a quote's embed at a width, as
+// the embedding page's styles decide the real one
+func embedHeight(quote models.Quote, width int) int {
+	perLine := max(width/embedCharWidth, 1)
+	lines := (utf8.RuneCountInString(quote.Content) + perLine - 1) / perLine
+	return embedChromeHeight + max(lines, 1)*embedLineHeight
+}
+
+// shareURL returns the address of a quote's share page
+func shareURL(base string, id uint) string {
+	return fmt.Sprintf("%s/q/%d", base, id)
+}
+
+// newSharePage describes a public quote loaded withResponseRelations
+func newSharePage(base string, quote models.Quote) sharePage {
+	size := card.Sizes[card.DefaultSize]
+	page := sharePage{
+		Quote:       quote,
+		SiteName:    siteName,
+		Title:       fmt.Sprintf("“%s” — %s", shortenText(quote.Content, feedTitleLength), quote.Author),
+		Description: shortenText(quote.Content, shareDescriptionLength) + " — " + quote.Author,
+		URL:         shareURL(base, quote.ID),
+		ImageURL:    fmt.Sprintf("%s/quotes/%d/card.png", base, quote.ID),
+		ImageWidth:  size.Width,
+		ImageHeight: size.Height,
+	}
+	page.OEmbedURL = base + "/oembed?" + url.Values{"url": {page.URL}, "format": {"json"}}.Encode()
+
+	// schema.org Quotation, as JSON-LD
+	page.JSONLD = map[string]interface{}{
+		"@context":      "https://schema.org",
+		"@type":         "Quotation",
+		"text":          quote.Content,
+		"creator":       map[string]interface{}{"@type": "Person", "name": quote.Author},
+		"url":           page.URL,
+		"image":         page.ImageURL,
+		"datePublished": quotePublished(quote).UTC().Format(time.RFC3339),
+		"dateModified":  quoteUpdated(quote).UTC().Format(time.RFC3339),
+		"interactionStatistic": map[string]interface{}{
+			"@type":                "InteractionCounter",
+			"interactionType":      "https://schema.org/LikeAction",
+			"userInteractionCount": len(quote.Votes),
+		},
+	}
+	if quote.Language != langdetect.Undetermined {
+		page.Lang = quote.Language
+		page.JSONLD["inLanguage"] = quote.Language
+	}
+	if source := quote.Source; source.WorkTitle != "" || source.URL != "" {
+		citation := map[string]interface{}{"@type": "CreativeWork"}
+		if source.WorkTitle != "" {
+			citation["name"] = source.WorkTitle
+		}
+		if source.URL != "" {
+			citation["url"] = source.URL
+		}
+		if source.Year != nil {
+			citation["datePublished"] = strconv.Itoa(*source.Year)
+		}
+		page.JSONLD["citation"] = citation
+	}
+	return page
+}
+
+// findPublicQuote loads a public quote withResponseRelations by its ID as
+// written in a path
+func findPublicQuote(id string) (models.Quote, bool) {
+	var quote models.Quote
+	parsed, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return quote, false
+	}
+	err = config.DB.Scopes(publiclyVisible, withResponseRelations).First(&quote, parsed).Error
+	return quote, err == nil
+}
+
+// GetSharePage renders a public quote as an HTML page, with the OpenGraph
+// and Twitter card tags link previews use and the quote as schema.org
+// JSON-LD
+func GetSharePage(c *gin.Context) {
+	var body bytes.Buffer
+	quote, found := findPublicQuote(c.Param("id"))
+	if !found {
+		if err := shareTemplates.ExecuteTemplate(&body, "notfound.html", nil); err != nil {
+			c.String(http.StatusInternalServerError, "Failed to render page")
+			return
+		}
+		c.Data(http.StatusNotFound, "text/html; charset=utf-8", body.Bytes())
+		return
+	}
+
+	if err := shareTemplates.ExecuteTemplate(&body, "share.html", newSharePage(publicURL(c), quote)); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+	c.Header("Cache-Control", linksCacheControl(shareMaxAge))
+	if notModified(c, bodyETag(body.Bytes())) {
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+// GetOEmbed describes a share page for embedding on other sites, following
+// the oEmbed spec. Only JSON responses are supported.
+func GetOEmbed(c *gin.Context) {
+	if format := c.DefaultQuery("format", "json"); format != "json" {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Only the json format is supported"})
+		return
+	}
+	width := defaultEmbedWidth
+	if value := c.Query("maxwidth"); value != "" {
+		maxWidth, err := strconv.Atoi(value)
+		if err != nil || maxWidth < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "maxwidth must be a positive integer"})
+			return
+		}
+		width = min(width, maxWidth)
+	}
+	maxHeight := 0
+	if value := c.Query("maxheight"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "maxheight must be a positive integer"})
+			return
+		}
+		maxHeight = parsed
+	}
+
+	base := publicURL(c)
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	target, err := url.Parse(c.Query("url"))
+	var match []string
+	if err == nil && target.Host == baseURL.Host {
+		match = sharePath.FindStringSubmatch(target.Path)
+	}
+	if match == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not a quote URL"})
+		return
+	}
+	quote, found := findPublicQuote(match[1])
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	// Quotes are never cut short, so an embed that does not fit is refused
+	height := embedHeight(quote, width)
+	if maxHeight > 0 && height > maxHeight {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote does not fit in maxheight", "height": height})
+		return
+	}
+
+	page := newSharePage(base, quote)
+	var html bytes.Buffer
+	if err := shareTemplates.ExecuteTemplate(&html, "embed.html", page); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render embed"})
+		return
+	}
+	c.Header("Cache-Control", linksCacheControl(shareMaxAge))
+	c.JSON(http.StatusOK, oEmbedResponse{
+		Version:         "1.0",
+		Type:            "rich",
+		Title:           page.Title,
+		AuthorName:      quote.Author,
+		ProviderName:    siteName,
+		ProviderURL:     base,
+		CacheAge:        oEmbedCacheAge,
+		ThumbnailURL:    page.ImageURL,
+		ThumbnailWidth:  page.ImageWidth,
+		ThumbnailHeight: page.ImageHeight,
+		HTML:            html.String(),
+		Width:           width,
+		Height:          height,
+	})
+}
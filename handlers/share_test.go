@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupShareTest() (*gin.Engine, []models.Quote) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	year := 1759
+	quotes := []models.Quote{
+		{Content: "Il faut cultiver notre jardin.", Author: "Voltaire", Language: "fr", Source: models.Citation{WorkTitle: "Candide", Year: &year}},
+		{Content: `Tags like </script><script>alert("hi")</script> stay text.`, Author: "Mallory <b>"},
+		{Content: "Waiting for review.", Author: "Someone", Status: models.QuoteStatusPending},
+	}
+	db.Create(&quotes)
+	db.Create(&models.Vote{QuoteID: quotes[0].ID})
+
+	r := gin.Default()
+	r.GET("/q/:id", GetSharePage)
+	r.GET("/oembed", GetOEmbed)
+	return r, quotes
+}
+
+func getShared(r *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Host = "quotes.example.com"
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// jsonLD decodes the JSON-LD script of a page
+func jsonLD(t *testing.T, page string) map[string]interface{} {
+	_, script, found := strings.Cut(page, `<script type="application/ld+json">`)
+	require.True(t, found)
+	script, _, found = strings.Cut(script, "</script>")
+	require.True(t, found)
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(script), &data))
+	return data
+}
+
+func TestSharePage(t *testing.T) {
+	r, quotes := setupShareTest()
+
+	w := getShared(r, fmt.Sprintf("/q/%d", quotes[0].ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	page := w.Body.String()
+	pageURL := fmt.Sprintf("http://quotes.example.com/q/%d", quotes[0].ID)
+	assert.Contains(t, page, `<html lang="fr">`)
+	assert.Contains(t, page, `<title>“Il faut cultiver notre jardin.” — Voltaire</title>`)
+	assert.Contains(t, page, `<meta property="og:url" content="`+pageURL+`">`)
+	assert.Contains(t, page, fmt.Sprintf(`<meta property="og:image" content="http://quotes.example.com/quotes/%d/card.png">`, quotes[0].ID))
+	assert.Contains(t, page, `<meta property="og:image:width" content="1200">`)
+	assert.Contains(t, page, `<meta name="twitter:card" content="summary_large_image">`)
+	assert.Contains(t, page, `<link rel="alternate" type="application/json+oembed" href="http://quotes.example.com/oembed?format=json&amp;url=`+url.QueryEscape(pageURL)+`"`)
+
+	data := jsonLD(t, page)
+	assert.Equal(t, "Quotation", data["@type"])
+	assert.Equal(t, "Il faut cultiver notre jardin.", data["text"])
+	assert.Equal(t, map[string]interface{}{"@type": "Person", "name": "Voltaire"}, data["creator"])
+	assert.Equal(t, map[string]interface{}{"@type": "CreativeWork", "name": "Candide", "datePublished": "1759"}, data["citation"])
+	assert.Equal(t, "fr", data["inLanguage"])
+	assert.Equal(t, float64(1), data["interactionStatistic"].(map[string]interface{})["userInteractionCount"])
+
+	// Pages revalidate with their ETag
+	w = getShared(r, fmt.Sprintf("/q/%d", quotes[0].ID), map[string]string{"If-None-Match": w.Header().Get("ETag")})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	for _, path := range []string{fmt.Sprintf("/q/%d", quotes[2].ID), "/q/999", "/q/abc"} {
+		w = getShared(r, path, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.Contains(t, w.Body.String(), "<title>Quote not found</title>")
+	}
+}
+
+func TestSharePageEscaping(t *testing.T) {
+	r, quotes := setupShareTest()
+
+	w := getShared(r, fmt.Sprintf("/q/%d", quotes[1].ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	page := w.Body.String()
+	assert.NotContains(t, page, "<script>alert")
+	assert.NotContains(t, page, "Mallory <b>")
+	assert.Equal(t, 1, strings.Count(page, "</script>"), "only the JSON-LD script is closed")
+	assert.NotContains(t, page, "<html lang", "undetermined languages are left out")
+
+	data := jsonLD(t, page)
+	assert.Equal(t, quotes[1].Content, data["text"])
+	assert.Nil(t, data["citation"])
+}
+
+func TestOEmbed(t *testing.T) {
+	r, quotes := setupShareTest()
+	pageURL := fmt.Sprintf("http://quotes.example.com/q/%d", quotes[0].ID)
+
+	w := getShared(r, "/oembed?url="+url.QueryEscape(pageURL), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var embed map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &embed))
+	assert.Equal(t, "1.0", embed["version"])
+	assert.Equal(t, "rich", embed["type"])
+	assert.Equal(t, "Voltaire", embed["author_name"])
+	assert.Equal(t, "http://quotes.example.com", embed["provider_url"])
+	assert.Equal(t, float64(550), embed["width"])
+	assert.Equal(t, float64(96), embed["height"], "one line of text and the attribution")
+	assert.Equal(t, fmt.Sprintf("http://quotes.example.com/quotes/%d/card.png", quotes[0].ID), embed["thumbnail_url"])
+	assert.Equal(t, `<blockquote class="quote-embed"><p>Il faut cultiver notre jardin.</p>&mdash; Voltaire, <cite>Candide</cite> <a href="`+pageURL+`">Quotes</a></blockquote>`+"\n", embed["html"])
+
+	w = getShared(r, "/oembed?maxwidth=300&url="+url.QueryEscape(pageURL), nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &embed))
+	assert.Equal(t, float64(300), embed["width"])
+
+	// Narrow embeds wrap onto more lines, and must fit in maxheight
+	w = getShared(r, "/oembed?maxwidth=60&url="+url.QueryEscape(pageURL), nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &embed))
+	assert.Equal(t, float64(192), embed["height"])
+	w = getShared(r, "/oembed?maxwidth=60&maxheight=192&url="+url.QueryEscape(pageURL), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &embed))
+	assert.Equal(t, float64(60), embed["width"])
+	assert.Equal(t, float64(192), embed["height"])
+	w = getShared(r, "/oembed?maxwidth=60&maxheight=150&url="+url.QueryEscape(pageURL), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code, "the quote is not cut short")
+
+	w = getShared(r, fmt.Sprintf("/oembed?url=%s", url.QueryEscape(fmt.Sprintf("http://quotes.example.com/q/%d", quotes[1].ID))), nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &embed))
+	assert.NotContains(t, embed["html"], "<script>")
+
+	assert.Equal(t, http.StatusNotImplemented, getShared(r, "/oembed?format=xml&url="+url.QueryEscape(pageURL), nil).Code)
+	assert.Equal(t, http.StatusBadRequest, getShared(r, "/oembed?maxwidth=wide&url="+url.QueryEscape(pageURL), nil).Code)
+	assert.Equal(t, http.StatusBadRequest, getShared(r, "/oembed?maxheight=0&url="+url.QueryEscape(pageURL), nil).Code)
+	for _, target := range []string{
+		fmt.Sprintf("http://elsewhere.example.com/q/%d", quotes[0].ID),
+		fmt.Sprintf("http://quotes.example.com/quotes/%d", quotes[0].ID),
+		fmt.Sprintf("http://quotes.example.com/q/%d", quotes[2].ID),
+		"",
+	} {
+		w = getShared(r, "/oembed?url="+url.QueryEscape(target), nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, target)
+	}
+}
+
+func TestLinksCacheControl(t *testing.T) {
+	responses := func() map[string]*httptest.ResponseRecorder {
+		shares, quotes := setupShareTest()
+		pageURL := fmt.Sprintf("http://quotes.example.com/q/%d", quotes[0].ID)
+		responses := map[string]*httptest.ResponseRecorder{
+			"page":   getShared(shares, fmt.Sprintf("/q/%d", quotes[0].ID), nil),
+			"oembed": getShared(shares, "/oembed?url="+url.QueryEscape(pageURL), nil),
+		}
+		feeds, _ := setupSyndicationTest()
+		responses["feed"] = getFeed(feeds, "/feeds/quotes.atom", nil)
+		return responses
+	}
+
+	// Links built from the Host header must not reach shared caches
+	for name, w := range responses() {
+		require.Equal(t, http.StatusOK, w.Code, name)
+		assert.Equal(t, "private, max-age=300", w.Header().Get("Cache-Control"), name)
+	}
+
+	os.Setenv("PUBLIC_URL", "http://quotes.example.com/")
+	defer os.Unsetenv("PUBLIC_URL")
+	for name, w := range responses() {
+		require.Equal(t, http.StatusOK, w.Code, name)
+		assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"), name)
+	}
+}
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"Qoute-backend/config"
+	"Qoute-backend/langdetect"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	syndicationSize   = 50                 // Entries per feed
+	topFeedPeriod     = 7 * 24 * time.Hour // Votes counted by the top quotes feed
+	feedTitleLength   = 80                 // Runes of a quote shown as an entry title
+	syndicationMaxAge = 300                // Seconds feeds may be cached for
+)
+
+// Syndication feed formats
+const (
+	feedAtom = "atom"
+	feedRSS  = "rss"
+)
+
+// feedContentTypes maps syndication feed formats to their content types
+var feedContentTypes = map[string]string{
+	feedAtom: "application/atom+xml; charset=utf-8",
+	feedRSS:  "application/rss+xml; charset=utf-8",
+}
+
+// atomFeed is an Atom feed, RFC 4287
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Lang      string     `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Author    atomPerson `xml:"author"`
+	Links     []atomLink `xml:"link"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// rssFeed is an RSS 2.0 feed
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	Description string  `xml:"description"`
+	Creator     string  `xml:"dc:creator"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// GetQuotesAtom returns the newest public quotes as an Atom feed
+func GetQuotesAtom(c *gin.Context) {
+	respondWithSyndicationFeed(c, false, feedAtom)
+}
+
+// GetQuotesRSS returns the newest public quotes as an RSS feed
+func GetQuotesRSS(c *gin.Context) {
+	respondWithSyndicationFeed(c, false, feedRSS)
+}
+
+// GetTopQuotesAtom returns the public quotes with the most votes this week
+// as an Atom feed
+func GetTopQuotesAtom(c *gin.Context) {
+	respondWithSyndicationFeed(c, true, feedAtom)
+}
+
+// GetTopQuotesRSS returns the public quotes with the most votes this week as
+// an RSS feed
+func GetTopQuotesRSS(c *gin.Context) {
+	respondWithSyndicationFeed(c, true, feedRSS)
+}
+
+// publicURL returns the address the API is reached at, from PUBLIC_URL or
+// else the request
+func publicURL(c *gin.Context) string {
+	if base := config.PublicURL(); base != "" {
+		return base
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// linksCacheControl returns the Cache-Control header of a response holding
+// links from publicURL. Without PUBLIC_URL the links follow the request's
+// Host header, so shared caches must not serve them to other clients.
+func linksCacheControl(maxAge int) string {
+	if config.PublicURL() == "" {
+		return fmt.Sprintf("private, max-age=%d", maxAge)
+	}
+	return fmt.Sprintf("public, max-age=%d", maxAge)
+}
+
+// quotePublished returns when a quote went public: its scheduled time, or
+// else its creation
+func quotePublished(quote models.Quote) time.Time {
+	if quote.PublishAt != nil {
+		return *quote.PublishAt
+	}
+	return quote.CreatedAt
+}
+
+// quoteUpdated returns when a quote last changed. Quotes edited before their
+// scheduled publication count as updated when they were published.
+func quoteUpdated(quote models.Quote) time.Time {
+	if published := quotePublished(quote); published.After(quote.UpdatedAt) {
+		return published
+	}
+	return quote.UpdatedAt
+}
+
+// shortenText shortens text to one line of at most length runes
+func shortenText(text string, length int) string {
+	line := strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(line) <= length {
+		return line
+	}
+	runes := []rune(line)[:length-1]
+	return strings.TrimRight(string(runes), " ,;:.") + "…"
+}
+
+// quoteAttribution returns the quote followed by its author and work
+func quoteAttribution(quote models.Quote) string {
+	attribution := quote.Author
+	if quote.Source.WorkTitle != "" {
+		attribution += ", " + quote.Source.WorkTitle
+	}
+	return quote.Content + "\n\n— " + attribution
+}
+
+// newestQuotes loads the newest public quotes. Scheduled quotes count from
+// their publication.
+func newestQuotes(author string) ([]models.Quote, time.Time, error) {
+	db := config.DB.Scopes(publiclyVisible)
+	if author != "" {
+		db = db.Where("quotes.author = ?", author)
+	}
+	var quotes []models.Quote
+	err := db.Order("COALESCE(quotes.publish_at, quotes.created_at) DESC, quotes.id DESC").
+		Limit(syndicationSize).Find(&quotes).Error
+	return quotes, time.Time{}, err
+}
+
+// topQuotes loads the public quotes with the most votes cast since the
+// start of the period, and when the newest of those votes was cast, since it
+// changes the ranking
+func topQuotes(author string, since time.Time) ([]models.Quote, time.Time, error) {
+	var ranked []struct {
+		ID    uint
+		Votes int
+	}
+	db := config.DB.Model(&models.Quote{}).Scopes(publiclyVisible).
+		Select("quotes.id AS id, COUNT(votes.id) AS votes").
+		Joins("JOIN votes ON votes.quote_id = quotes.id AND votes.created_at >= ?", since).
+		Group("quotes.id")
+	if author != "" {
+		db = db.Where("quotes.author = ?", author)
+	}
+	if err := db.Order("votes DESC, quotes.id DESC").Limit(syndicationSize).Scan(&ranked).Error; err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(ranked) == 0 {
+		return nil, time.Time{}, nil
+	}
+
+	ids := make([]uint, len(ranked))
+	for i, quote := range ranked {
+		ids[i] = quote.ID
+	}
+	var loaded []models.Quote
+	if err := config.DB.Where("id IN ?", ids).Find(&loaded).Error; err != nil {
+		return nil, time.Time{}, err
+	}
+	byID := make(map[uint]models.Quote, len(loaded))
+	for _, quote := range loaded {
+		byID[quote.ID] = quote
+	}
+	quotes := make([]models.Quote, 0, len(ids))
+	for _, id := range ids {
+		if quote, ok := byID[id]; ok {
+			quotes = append(quotes, quote)
+		}
+	}
+
+	var newest models.Vote
+	if err := config.DB.Where("quote_id IN ? AND created_at >= ?", ids, since).Order("created_at desc").First(&newest).Error; err != nil {
+		return nil, time.Time{}, err
+	}
+	return quotes, newest.CreatedAt, nil
+}
+
+// respondWithSyndicationFeed writes the newest or the top quotes as an Atom
+// or RSS feed. The feed is updated when its newest entry was. Conditional
+// requests only use the ETag of the body, since the feed also changes when
+// entries are removed, which moves no entry's time.
+func respondWithSyndicationFeed(c *gin.Context, top bool, format string) {
+	if c.Query("tag") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quotes have no tags, so feeds cannot be filtered by tag"})
+		return
+	}
+	author := strings.TrimSpace(c.Query("author"))
+
+	var (
+		quotes  []models.Quote
+		updated time.Time
+		err     error
+	)
+	title, path := "New quotes", "/feeds/quotes."+format
+	if top {
+		title, path = "Top quotes this week", "/feeds/top."+format
+		quotes, updated, err = topQuotes(author, time.Now().Add(-topFeedPeriod))
+	} else {
+		quotes, updated, err = newestQuotes(author)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, quote := range quotes {
+		if quoteUpdated(quote).After(updated) {
+			updated = quoteUpdated(quote)
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Unix(0, 0) // An empty feed has never changed
+	}
+
+	base := publicURL(c)
+	query := ""
+	if author != "" {
+		title += " by " + author
+		query = "?" + url.Values{"author": {author}}.Encode()
+	}
+	self := base + path + query
+	alternate := base + "/quotes" + query
+	quoteURL := func(quote models.Quote) string {
+		return shareURL(base, quote.ID)
+	}
+	// Entry IDs predate the share pages and must not change, or readers
+	// would show every quote again as a new entry
+	entryID := func(quote models.Quote) string {
+		return fmt.Sprintf("%s/quotes/%d", base, quote.ID)
+	}
+
+	var feed interface{}
+	switch format {
+	case feedAtom:
+		atom := atomFeed{
+			Title:   title,
+			ID:      self,
+			Updated: updated.UTC().Format(time.RFC3339),
+			Links:   []atomLink{{Rel: "self", Type: feedContentTypes[feedAtom], Href: self}, {Rel: "alternate", Href: alternate}},
+			Entries: make([]atomEntry, len(quotes)),
+		}
+		for i, quote := range quotes {
+			entry := atomEntry{
+				Title:     shortenText(quote.Content, feedTitleLength),
+				ID:        entryID(quote),
+				Published: quotePublished(quote).UTC().Format(time.RFC3339),
+				Updated:   quoteUpdated(quote).UTC().Format(time.RFC3339),
+				Author:    atomPerson{Name: quote.Author},
+				Links:     []atomLink{{Rel: "alternate", Href: quoteURL(quote)}},
+				Content:   atomText{Type: "text", Text: quoteAttribution(quote)},
+			}
+			if quote.Language != langdetect.Undetermined {
+				entry.Lang = quote.Language
+			}
+			atom.Entries[i] = entry
+		}
+		feed = atom
+	case feedRSS:
+		rss := rssFeed{
+			Version: "2.0",
+			Atom:    "http://www.w3.org/2005/Atom",
+			DC:      "http://purl.org/dc/elements/1.1/",
+			Channel: rssChannel{
+				Title:         title,
+				Link:          alternate,
+				Description:   title,
+				LastBuildDate: updated.UTC().Format(time.RFC1123Z),
+				Self:          atomLink{Rel: "self", Type: feedContentTypes[feedRSS], Href: self},
+				Items:         make([]rssItem, len(quotes)),
+			},
+		}
+		for i, quote := range quotes {
+			rss.Channel.Items[i] = rssItem{
+				Title:       shortenText(quote.Content, feedTitleLength),
+				Link:        quoteURL(quote),
+				GUID:        rssGUID{IsPermaLink: true, Value: entryID(quote)},
+				Description: quoteAttribution(quote),
+				Creator:     quote.Author,
+				PubDate:     quotePublished(quote).UTC().Format(time.RFC1123Z),
+			}
+		}
+		feed = rss
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	body = append([]byte(xml.Header), append(body, '\n')...)
+	c.Header("Cache-Control", linksCacheControl(syndicationMaxAge))
+	if notModified(c, bodyETag(body)) {
+		return
+	}
+	c.Data(http.StatusOK, feedContentTypes[format], body)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSyndicationTest() (*gin.Engine, []models.Quote) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+
+	now := time.Now().UTC().Truncate(time.Second)
+	future := now.Add(time.Hour)
+	quotes := []models.Quote{
+		{Content: "Stay hungry, stay foolish.", Author: "Steve Jobs", Language: "en", CreatedAt: now.Add(-72 * time.Hour), UpdatedAt: now.Add(-time.Hour)},
+		{Content: "Be the change that you wish to see in the world.", Author: "Mahatma Gandhi", CreatedAt: now.Add(-48 * time.Hour), UpdatedAt: now.Add(-48 * time.Hour)},
+		{Content: "Simplicity is the ultimate sophistication.", Author: "Leonardo da Vinci", Source: models.Citation{WorkTitle: "Notebooks"}, CreatedAt: now.Add(-24 * time.Hour), UpdatedAt: now.Add(-24 * time.Hour)},
+		{Content: "Waiting for review.", Author: "Someone", Status: models.QuoteStatusPending},
+		{Content: "Not out yet.", Author: "Someone", PublishAt: &future},
+	}
+	db.Create(&quotes)
+
+	r := gin.Default()
+	r.GET("/feeds/quotes.atom", GetQuotesAtom)
+	r.GET("/feeds/quotes.rss", GetQuotesRSS)
+	r.GET("/feeds/top.atom", GetTopQuotesAtom)
+	r.GET("/feeds/top.rss", GetTopQuotesRSS)
+	return r, quotes
+}
+
+func getFeed(r *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Host = "quotes.example.com"
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestQuotesAtomFeed(t *testing.T) {
+	r, quotes := setupSyndicationTest()
+
+	w := getFeed(r, "/feeds/quotes.atom", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), xml.Header))
+
+	var feed atomFeed
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+	assert.Equal(t, "New quotes", feed.Title)
+	assert.Equal(t, "http://quotes.example.com/feeds/quotes.atom", feed.ID)
+	assert.Equal(t, quotes[0].UpdatedAt.Format(time.RFC3339), feed.Updated, "the newest change among the entries")
+	require.Len(t, feed.Entries, 3, "only public quotes are listed")
+
+	entry := feed.Entries[0]
+	assert.Equal(t, fmt.Sprintf("http://quotes.example.com/quotes/%d", quotes[2].ID), entry.ID, "IDs stay as they were before share pages")
+	require.Len(t, entry.Links, 1)
+	assert.Equal(t, fmt.Sprintf("http://quotes.example.com/q/%d", quotes[2].ID), entry.Links[0].Href)
+	assert.Equal(t, "Leonardo da Vinci", entry.Author.Name)
+	assert.Equal(t, "Simplicity is the ultimate sophistication.\n\n— Leonardo da Vinci, Notebooks", entry.Content.Text)
+	assert.Empty(t, entry.Lang)
+	last := feed.Entries[2]
+	assert.Equal(t, quotes[0].CreatedAt.Format(time.RFC3339), last.Published)
+	assert.Equal(t, quotes[0].UpdatedAt.Format(time.RFC3339), last.Updated)
+	assert.Equal(t, "en", last.Lang)
+
+	// Feeds can follow one author
+	w = getFeed(r, "/feeds/quotes.atom?author=Mahatma+Gandhi", nil)
+	feed = atomFeed{}
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+	assert.Equal(t, "New quotes by Mahatma Gandhi", feed.Title)
+	assert.Equal(t, "http://quotes.example.com/feeds/quotes.atom?author=Mahatma+Gandhi", feed.ID)
+	require.Len(t, feed.Entries, 1)
+	assert.Equal(t, quotes[1].UpdatedAt.Format(time.RFC3339), feed.Updated)
+
+	w = getFeed(r, "/feeds/quotes.atom?tag=wisdom", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestQuotesRSSFeed(t *testing.T) {
+	r, quotes := setupSyndicationTest()
+
+	w := getFeed(r, "/feeds/quotes.rss", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/rss+xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">`)
+	assert.Contains(t, w.Body.String(), `<atom:link rel="self" type="application/rss+xml; charset=utf-8" href="http://quotes.example.com/feeds/quotes.rss"></atom:link>`)
+
+	var feed rssFeed
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+	assert.Equal(t, quotes[0].UpdatedAt.Format(time.RFC1123Z), feed.Channel.LastBuildDate)
+	require.Len(t, feed.Channel.Items, 3)
+	item := feed.Channel.Items[1]
+	assert.Equal(t, "Be the change that you wish to see in the world.", item.Title)
+	assert.Equal(t, fmt.Sprintf("http://quotes.example.com/q/%d", quotes[1].ID), item.Link)
+	assert.Equal(t, fmt.Sprintf("http://quotes.example.com/quotes/%d", quotes[1].ID), item.GUID.Value)
+	assert.True(t, item.GUID.IsPermaLink)
+	assert.Equal(t, quotes[1].CreatedAt.Format(time.RFC1123Z), item.PubDate)
+	assert.Contains(t, w.Body.String(), "<dc:creator>Mahatma Gandhi</dc:creator>")
+}
+
+func TestTopQuotesFeed(t *testing.T) {
+	r, quotes := setupSyndicationTest()
+	db := config.DB
+	now := time.Now().UTC().Truncate(time.Second)
+	votes := []models.Vote{
+		{QuoteID: quotes[1].ID, CreatedAt: now.Add(-2 * time.Hour)},
+		{QuoteID: quotes[1].ID, CreatedAt: now.Add(-3 * time.Hour)},
+		{QuoteID: quotes[0].ID, CreatedAt: now.Add(-30 * time.Minute)},
+		{QuoteID: quotes[2].ID, CreatedAt: now.Add(-10 * 24 * time.Hour)}, // Too old to count
+		{QuoteID: quotes[3].ID, CreatedAt: now},                           // Not public
+	}
+	db.Create(&votes)
+
+	w := getFeed(r, "/feeds/top.atom", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var feed atomFeed
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+	assert.Equal(t, "Top quotes this week", feed.Title)
+	require.Len(t, feed.Entries, 2)
+	assert.Equal(t, "Mahatma Gandhi", feed.Entries[0].Author.Name)
+	assert.Equal(t, "Steve Jobs", feed.Entries[1].Author.Name)
+	assert.Equal(t, votes[2].CreatedAt.Format(time.RFC3339), feed.Updated, "new votes change the ranking")
+
+	w = getFeed(r, "/feeds/top.rss?author=Steve+Jobs", nil)
+	var rss rssFeed
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &rss))
+	assert.Equal(t, "Top quotes this week by Steve Jobs", rss.Channel.Title)
+	assert.Len(t, rss.Channel.Items, 1)
+}
+
+func TestSyndicationConditionalGet(t *testing.T) {
+	r, quotes := setupSyndicationTest()
+
+	w := getFeed(r, "/feeds/quotes.atom", nil)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Empty(t, w.Header().Get("Last-Modified"))
+
+	w = getFeed(r, "/feeds/quotes.atom", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	w = getFeed(r, "/feeds/quotes.atom", map[string]string{"If-Modified-Since": time.Now().UTC().Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusOK, w.Code, "only the ETag is used")
+
+	// Removing an older quote changes the feed without changing any entry
+	require.NoError(t, config.DB.Delete(&quotes[2]).Error)
+	w = getFeed(r, "/feeds/quotes.atom", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	removed := w.Header().Get("ETag")
+	assert.NotEqual(t, etag, removed)
+
+	// Editing a quote updates the feed
+	require.NoError(t, config.DB.Model(&quotes[1]).Updates(map[string]interface{}{"content": "Be the change.", "version": bumpVersion}).Error)
+	w = getFeed(r, "/feeds/quotes.atom", map[string]string{"If-None-Match": removed})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, removed, w.Header().Get("ETag"))
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"Qoute-backend/config"
+	"Qoute-backend/jobs"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrashedQuote is a soft deleted quote and when it will be purged
+type TrashedQuote struct {
+	models.Quote
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// GetTrash lists deleted quotes that can still be restored: the caller's
+// own, or every one for moderators
+func GetTrash(c *gin.Context) {
+	viewer, err := loadQuoteViewer(c, config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	db := config.DB.Unscoped().Where("deleted_at IS NOT NULL")
+	if !viewer.canModerate() {
+		db = db.Where("quotes.user_id = ?", viewer.userID)
+	}
+
+	var quotes []models.Quote
+	if err := db.Order("deleted_at desc").Find(&quotes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	retention := config.TrashRetention()
+	response := make([]TrashedQuote, len(quotes))
+	for i, quote := range quotes {
+		response[i] = TrashedQuote{
+			Quote:     quote,
+			DeletedAt: quote.DeletedAt.Time,
+			PurgeAt:   quote.DeletedAt.Time.Add(retention),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// removedByModerator reports whether a quote was moved to the trash by a
+// moderator resolving a report on it
+func removedByModerator(db *gorm.DB, quoteID uint) (bool, error) {
+	var removals int64
+	err := db.Model(&models.Report{}).
+		Where("quote_id = ? AND status = ? AND (resolution = ? OR resolution LIKE ?)", quoteID, models.ReportStatusResolved, ReportActionRemove, ReportActionRemove+": %").
+		Count(&removals).Error
+	return removals > 0, err
+}
+
+// RestoreQuote moves a quote out of the trash. Users can restore their own
+// quotes, unless a moderator removed them. Moderators can restore any quote.
+func RestoreQuote(c *gin.Context) {
+	id := c.Param("id")
+	var quote models.Quote
+
+	viewer, err := loadQuoteViewer(c, config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := config.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&quote, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found in trash"})
+		return
+	}
+
+	if !viewer.canModerate() {
+		if quote.UserID == nil || *quote.UserID != viewer.userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only restore your own quotes"})
+			return
+		}
+		removed, err := removedByModerator(config.DB, quote.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if removed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Quote was removed by a moderator"})
+			return
+		}
+	}
+
+	if err := config.DB.Unscoped().Model(&quote).Updates(map[string]interface{}{"deleted_at": nil, "version": bumpVersion}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore quote"})
+		return
+	}
+	if err := config.DB.First(&quote, quote.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load restored quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quote restored successfully", "quote": quote})
+}
+
+// PurgeQuote permanently deletes a quote and its votes, whether or not it is
+// in the trash
+func PurgeQuote(c *gin.Context) {
+	id := c.Param("id")
+	var quote models.Quote
+
+	if err := config.DB.Unscoped().First(&quote, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return jobs.PurgeQuotes(tx, []uint{quote.ID})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quote permanently deleted"})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	owner := models.User{Username: "trashowner", Password: "hashed"}
+	db.Create(&owner)
+	quote := models.Quote{Content: "trash me", Author: "bin", UserID: &owner.ID}
+	db.Create(&quote)
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", owner.ID)
+		c.Next()
+	})
+	r.GET("/quotes/trash", GetTrash)
+	r.GET("/quotes/:id", GetQuote)
+	r.DELETE("/quotes/:id", DeleteQuote)
+	r.POST("/quotes/:id/restore", RestoreQuote)
+	r.DELETE("/quotes/:id/permanent", PurgeQuote)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("DELETE", fmt.Sprintf("/quotes/%d", quote.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", fmt.Sprintf("/quotes/%d", quote.ID)).Code)
+
+	// The deleted quote is listed in the trash with its purge date
+	w := serve("GET", "/quotes/trash")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var trash []map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &trash)
+	assert.Len(t, trash, 1)
+	assert.Equal(t, "trash me", trash[0]["content"])
+	assert.NotEmpty(t, trash[0]["deleted_at"])
+	assert.NotEmpty(t, trash[0]["purge_at"])
+
+	// Restoring brings it back
+	assert.Equal(t, http.StatusOK, serve("POST", fmt.Sprintf("/quotes/%d/restore", quote.ID)).Code)
+	assert.Equal(t, http.StatusOK, serve("GET", fmt.Sprintf("/quotes/%d", quote.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, serve("POST", fmt.Sprintf("/quotes/%d/restore", quote.ID)).Code)
+
+	// Permanent deletion also removes its votes
+	user := models.User{Username: "trashvoter", Password: "hashed"}
+	db.Create(&user)
+	db.Create(&models.Vote{QuoteID: quote.ID, UserID: &user.ID, VoterHash: ptr(config.VoterHash(user.ID))})
+	assert.Equal(t, http.StatusOK, serve("DELETE", fmt.Sprintf("/quotes/%d/permanent", quote.ID)).Code)
+
+	var quotes, votes int64
+	db.Unscoped().Model(&models.Quote{}).Count(&quotes)
+	db.Model(&models.Vote{}).Count(&votes)
+	assert.Zero(t, quotes)
+	assert.Zero(t, votes)
+}
+
+func TestTrashPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupQuoteTestDB()
+	db := config.DB
+
+	owner := models.User{Username: "owner", Password: "hashed"}
+	other := models.User{Username: "other", Password: "hashed"}
+	moderator := models.User{Username: "moderator", Password: "hashed", Role: models.RoleModerator}
+	db.Create(&owner)
+	db.Create(&other)
+	db.Create(&moderator)
+	deleted := models.Quote{Content: "deleted by its owner", Author: "bin", UserID: &owner.ID}
+	removed := models.Quote{Content: "removed by a moderator", Author: "bin", UserID: &owner.ID}
+	db.Create(&deleted)
+	db.Create(&removed)
+	db.Create(&models.Report{QuoteID: removed.ID, UserID: other.ID, Reason: models.ReportReasonSpam, Status: models.ReportStatusResolved, Resolution: ReportActionRemove + ": spam"})
+	db.Delete(&deleted)
+	db.Delete(&removed)
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Query("as"))
+		c.Set("user_id", uint(id))
+		c.Next()
+	})
+	r.GET("/quotes/trash", GetTrash)
+	r.POST("/quotes/:id/restore", RestoreQuote)
+	serve := func(method, path string, user models.User) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, fmt.Sprintf("%s?as=%d", path, user.ID), nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	trash := func(user models.User) []TrashedQuote {
+		var quotes []TrashedQuote
+		json.Unmarshal(serve("GET", "/quotes/trash", user).Body.Bytes(), &quotes)
+		return quotes
+	}
+
+	// Users only see their own trash, moderators see everything
+	assert.Len(t, trash(owner), 2)
+	assert.Empty(t, trash(other))
+	assert.Len(t, trash(moderator), 2)
+
+	// Other users cannot restore the quote
+	assert.Equal(t, http.StatusForbidden, serve("POST", fmt.Sprintf("/quotes/%d/restore", deleted.ID), other).Code)
+
+	// The owner cannot undo a moderator's removal, but a moderator can
+	assert.Equal(t, http.StatusForbidden, serve("POST", fmt.Sprintf("/quotes/%d/restore", removed.ID), owner).Code)
+	w := serve("POST", fmt.Sprintf("/quotes/%d/restore", deleted.ID), owner)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var restored struct {
+		Quote map[string]interface{} `json:"quote"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &restored))
+	assert.Nil(t, restored.Quote["deleted_at"], "the restored quote is returned")
+	assert.EqualValues(t, deleted.Version+1, restored.Quote["version"])
+	assert.Equal(t, http.StatusOK, serve("POST", fmt.Sprintf("/quotes/%d/restore", removed.ID), moderator).Code)
+	assert.Empty(t, trash(owner))
+}
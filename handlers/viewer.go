@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"time"
+
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// quoteViewer describes who is reading quotes, so responses can be reduced
+// for anonymous visitors and personalised for signed in users
+type quoteViewer struct {
+	userID        uint
+	role          string
+	authenticated bool
+	votedQuoteIDs map[uint]bool
+}
+
+// loadQuoteViewer reads the optional user from the context set by the auth
+// middlewares and loads their personal state
+func loadQuoteViewer(c *gin.Context, db *gorm.DB) (quoteViewer, error) {
+	viewer := quoteViewer{votedQuoteIDs: map[uint]bool{}}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return viewer, nil
+	}
+	viewer.userID = userID.(uint)
+	viewer.authenticated = true
+
+	var user models.User
+	if err := db.Select("role").First(&user, viewer.userID).Error; err == nil {
+		viewer.role = user.Role
+	}
+
+	var votedIDs []uint
+	if err := db.Model(&models.Vote{}).Where("voter_hash = ?", config.VoterHash(viewer.userID)).Pluck("quote_id", &votedIDs).Error; err != nil {
+		return viewer, err
+	}
+	for _, id := range votedIDs {
+		viewer.votedQuoteIDs[id] = true
+	}
+	return viewer, nil
+}
+
+// canModerate reports whether the viewer can see and moderate every quote
+func (v quoteViewer) canModerate() bool {
+	return isModeratorRole(v.role)
+}
+
+// publishedSQL matches quotes whose scheduled publication time has passed
+const publishedSQL = "(quotes.publish_at IS NULL OR quotes.publish_at <= ?)"
+
+// publiclyVisible is a scope limiting quotes to approved and published ones
+// that have not been hidden because of reports
+func publiclyVisible(db *gorm.DB) *gorm.DB {
+	return db.Where("quotes.status = ? AND quotes.hidden = ? AND "+publishedSQL, models.QuoteStatusApproved, false, time.Now())
+}
+
+// visible is a scope limiting quotes to those the viewer may see: public
+// quotes, plus their own submissions including scheduled ones, or everything
+// for moderators
+func (v quoteViewer) visible(db *gorm.DB) *gorm.DB {
+	switch {
+	case v.canModerate():
+		return db
+	case v.authenticated:
+		return db.Where("(quotes.status = ? AND quotes.hidden = ? AND "+publishedSQL+") OR quotes.user_id = ?", models.QuoteStatusApproved, false, time.Now(), v.userID)
+	default:
+		return db.Scopes(publiclyVisible)
+	}
+}
+
+// withResponseRelations is a scope preloading what quote responses count:
+// the votes, and the IDs of comments that are not deleted
+func withResponseRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Votes").Preload("Comments", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "quote_id")
+	})
+}
+
+// response builds the response for a quote loaded withResponseRelations. Individual
+// votes are only listed to signed in users when voters are public.
+func (v quoteViewer) response(quote models.Quote) QuoteResponse {
+	response := QuoteResponse{
+		Quote:        quote,
+		VoteCount:    len(quote.Votes),
+		CommentCount: len(quote.Comments),
+	}
+	if !v.authenticated || config.VotePrivacy() != config.VotePrivacyPublic {
+		response.Votes = nil
+	}
+	if v.authenticated {
+		hasVoted := v.votedQuoteIDs[quote.ID]
+		response.HasVoted = &hasVoted
+	}
+	return response
+}
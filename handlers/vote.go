@@ -6,6 +6,7 @@ import (
 
     "github.com/gin-gonic/gin"
     "gorm.io/gorm"
+    "Qoute-backend/config"
     "Qoute-backend/models"
 )
 
@@ -42,16 +43,17 @@ func (h *VoteHandler) CreateVote(c *gin.Context) {
     }()
 
     // Check if user has already voted for any quote
+    voterHash := config.VoterHash(userID.(uint))
     var existingVote models.Vote
-    if err := tx.Where("user_id = ?", userID).First(&existingVote).Error; err == nil {
+    if err := tx.Where("voter_hash = ?", voterHash).First(&existingVote).Error; err == nil {
         tx.Rollback()
         c.JSON(http.StatusConflict, gin.H{"error": "You have already voted for a quote"})
         return
     }
 
-    // Check if quote exists
+    // Check if quote exists and is publicly visible
     var quote models.Quote
-    if err := tx.Preload("Votes").First(&quote, quoteID).Error; err != nil {
+    if err := tx.Scopes(publiclyVisible).Preload("Votes").First(&quote, quoteID).Error; err != nil {
         tx.Rollback()
         c.JSON(http.StatusNotFound, gin.H{"error": "Quote not found"})
         return
@@ -64,10 +66,15 @@ func (h *VoteHandler) CreateVote(c *gin.Context) {
         return
     }
 
-    // Create new vote
+    // Create new vote, leaving out the user in anonymous mode
+    privacy := config.VotePrivacy()
     vote := models.Vote{
-        UserID:  userID.(uint),
-        QuoteID: uint(quoteID),
+        VoterHash: &voterHash,
+        QuoteID:   uint(quoteID),
+    }
+    if privacy != config.VotePrivacyAnonymous {
+        uid := userID.(uint)
+        vote.UserID = &uid
     }
 
     if err := tx.Create(&vote).Error; err != nil {
@@ -76,8 +83,12 @@ func (h *VoteHandler) CreateVote(c *gin.Context) {
         return
     }
 
-    // Preload user data for response
-    if err := tx.Preload("User").First(&vote, vote.ID).Error; err != nil {
+    // Reload the vote, with user data only when voters are public
+    reload := tx
+    if privacy == config.VotePrivacyPublic {
+        reload = reload.Preload("User")
+    }
+    if err := reload.First(&vote, vote.ID).Error; err != nil {
         tx.Rollback()
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load vote metadata"})
         return
@@ -99,7 +110,8 @@ func (h *VoteHandler) CreateVote(c *gin.Context) {
     c.JSON(http.StatusCreated, gin.H{
         "message":   "Vote recorded successfully",
         "voteCount": voteCount,
-        "vote":      vote,
+        "vote":      redactVote(vote),
+        "receipt":   issueReceipt(vote),
     })
 }
 
@@ -128,7 +140,7 @@ func (h *VoteHandler) DeleteVote(c *gin.Context) {
     }()
 
     // Delete the vote
-    result := tx.Where("user_id = ? AND quote_id = ?", userID, quoteID).Delete(&models.Vote{})
+    result := tx.Where("voter_hash = ? AND quote_id = ?", config.VoterHash(userID.(uint)), quoteID).Delete(&models.Vote{})
     if result.Error != nil {
         tx.Rollback()
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete vote"})
@@ -206,7 +218,7 @@ func (h *VoteHandler) CheckUserVote(c *gin.Context) {
     }
 
     var vote models.Vote
-    err = h.db.Where("user_id = ? AND quote_id = ?", userID, quoteID).First(&vote).Error
+    err = h.db.Where("voter_hash = ? AND quote_id = ?", config.VoterHash(userID.(uint)), quoteID).First(&vote).Error
     if err != nil {
         if err == gorm.ErrRecordNotFound {
             c.JSON(http.StatusOK, gin.H{"has_voted": false})
@@ -218,4 +230,13 @@ func (h *VoteHandler) CheckUserVote(c *gin.Context) {
     }
 
     c.JSON(http.StatusOK, gin.H{"has_voted": true})
+}
+
+// redactVote hides the voter's identity unless voters are public
+func redactVote(vote models.Vote) models.Vote {
+    if config.VotePrivacy() != config.VotePrivacyPublic {
+        vote.UserID = nil
+        vote.User = nil
+    }
+    return vote
 }
\ No newline at end of file
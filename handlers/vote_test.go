@@ -178,3 +178,66 @@ func TestVoteOnlyWhenZeroVotes(t *testing.T) {
 	json.Unmarshal(w2.Body.Bytes(), &resp2)
 	assert.Contains(t, resp2["error"], "Voting is only allowed when the quote has 0 votes")
 }
+
+func TestAnonymousVotePrivacy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupInMemoryDB()
+	db := config.DB
+	os.Setenv("VOTE_PRIVACY", config.VotePrivacyAnonymous)
+	defer os.Unsetenv("VOTE_PRIVACY")
+
+	user := models.User{Username: "anonuser", Password: "hashed"}
+	db.Create(&user)
+	quote := models.Quote{Content: "anon", Author: "anonauthor"}
+	db.Create(&quote)
+	other := models.Quote{Content: "anon2", Author: "anonauthor"}
+	db.Create(&other)
+
+	r := gin.Default()
+	voteHandler := NewVoteHandler(db)
+	withUser := func(h gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("user_id", user.ID)
+			h(c)
+		}
+	}
+	r.POST("/quotes/:id/vote", withUser(voteHandler.CreateVote))
+	r.GET("/quotes/:id/vote/check", withUser(voteHandler.CheckUserVote))
+	r.GET("/quotes/:id", GetQuote)
+
+	w1 := httptest.NewRecorder()
+	req1, _ := http.NewRequest("POST", fmt.Sprintf("/quotes/%d/vote", quote.ID), nil)
+	r.ServeHTTP(w1, req1)
+	assert.Equal(t, http.StatusCreated, w1.Code)
+	var resp map[string]interface{}
+	json.Unmarshal(w1.Body.Bytes(), &resp)
+	vote := resp["vote"].(map[string]interface{})
+	assert.NotContains(t, vote, "user_id")
+	assert.NotContains(t, vote, "user")
+
+	// Only the voter hash is stored
+	var stored models.Vote
+	db.First(&stored)
+	assert.Nil(t, stored.UserID)
+	assert.NotNil(t, stored.VoterHash)
+
+	// The one-vote rule still applies through the hash
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("POST", fmt.Sprintf("/quotes/%d/vote", other.ID), nil)
+	r.ServeHTTP(w2, req2)
+	assert.Equal(t, http.StatusConflict, w2.Code)
+
+	w3 := httptest.NewRecorder()
+	req3, _ := http.NewRequest("GET", fmt.Sprintf("/quotes/%d/vote/check", quote.ID), nil)
+	r.ServeHTTP(w3, req3)
+	assert.JSONEq(t, `{"has_voted": true}`, w3.Body.String())
+
+	// Quote responses only carry the count
+	w4 := httptest.NewRecorder()
+	req4, _ := http.NewRequest("GET", fmt.Sprintf("/quotes/%d", quote.ID), nil)
+	r.ServeHTTP(w4, req4)
+	var quoteResp map[string]interface{}
+	json.Unmarshal(w4.Body.Bytes(), &quoteResp)
+	assert.EqualValues(t, 1, quoteResp["voteCount"])
+	assert.NotContains(t, quoteResp, "votes")
+}
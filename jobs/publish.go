@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"Qoute-backend/events"
+	"Qoute-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// PublishDue marks approved quotes whose publication time has passed as
+// published and emits a published event for each, returning how many went
+// live. Quotes approved after their publication time go live once approved.
+// Quotes are claimed one by one, so several instances sharing a database
+// never announce a quote twice.
+func PublishDue(db *gorm.DB, bus *events.Bus, now time.Time) (int, error) {
+	var due []uint
+	err := db.Model(&models.Quote{}).
+		Where("published_at IS NULL AND status = ? AND (publish_at IS NULL OR publish_at <= ?)", models.QuoteStatusApproved, now).
+		Order("publish_at asc, id asc").Pluck("id", &due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, id := range due {
+		result := db.Model(&models.Quote{}).Where("id = ? AND published_at IS NULL", id).
+			Updates(map[string]interface{}{"published_at": now, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return published, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue // Claimed by another instance
+		}
+		published++
+		bus.Publish(events.Event{Type: events.QuotePublished, QuoteID: id, At: now})
+	}
+	return published, nil
+}
+
+// StartPublisher publishes due quotes every interval until ctx is cancelled
+func StartPublisher(ctx context.Context, db *gorm.DB, bus *events.Bus, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := PublishDue(db, bus, time.Now()); err != nil {
+			log.Printf("Failed to publish scheduled quotes: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
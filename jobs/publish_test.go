@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"Qoute-backend/config"
+	"Qoute-backend/events"
+	"Qoute-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishDue(t *testing.T) {
+	os.Setenv("DATABASE_DSN", ":memory:")
+	config.InitDB()
+	db := config.DB
+
+	now := time.Now()
+	later := now.Add(time.Hour)
+	immediate := models.Quote{Content: "immediate", Author: "a"}
+	scheduled := models.Quote{Content: "scheduled", Author: "a", PublishAt: &later}
+	pending := models.Quote{Content: "pending", Author: "a", Status: models.QuoteStatusPending}
+	db.Create(&immediate)
+	db.Create(&scheduled)
+	db.Create(&pending)
+
+	var bus events.Bus
+	var announced []uint
+	bus.Subscribe(events.QuotePublished, func(e events.Event) { announced = append(announced, e.QuoteID) })
+
+	published, err := PublishDue(db, &bus, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []uint{immediate.ID}, announced)
+
+	// Nothing is announced twice
+	published, err = PublishDue(db, &bus, now)
+	assert.NoError(t, err)
+	assert.Zero(t, published)
+
+	// Scheduled quotes go live at their time, pending ones once approved
+	db.Model(&pending).Update("status", models.QuoteStatusApproved)
+	published, err = PublishDue(db, &bus, later.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []uint{immediate.ID, pending.ID, scheduled.ID}, announced)
+
+	db.First(&scheduled, scheduled.ID)
+	assert.NotNil(t, scheduled.PublishedAt)
+	assert.EqualValues(t, 2, scheduled.Version)
+}
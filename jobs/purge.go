@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"Qoute-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// PurgeQuotes permanently deletes quotes, including soft deleted ones, and
+// every row that references them. It should run inside a transaction.
+func PurgeQuotes(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Where("quote_id IN ?", ids).Delete(&models.Vote{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("quote_a_id IN ? OR quote_b_id IN ?", ids, ids).Delete(&models.Battle{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("quote_id IN ?", ids).Delete(&models.DailyQuote{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("quote_id IN ?", ids).Delete(&models.Report{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("quote_id IN ?", ids).Delete(&models.CollectionItem{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("quote_id IN ?", ids).Delete(&models.Comment{}).Error; err != nil {
+		return err
+	}
+	// Translations outlive their original and become standalone quotes
+	if err := tx.Unscoped().Model(&models.Quote{}).Where("translation_of IN ?", ids).Updates(map[string]interface{}{"translation_of": nil, "version": gorm.Expr("version + 1")}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Quote{}).Error
+}
+
+// PurgeTrash permanently deletes quotes that were moved to the trash before
+// cutoff and returns how many were purged
+func PurgeTrash(db *gorm.DB, cutoff time.Time) (int, error) {
+	var ids []uint
+	if err := db.Unscoped().Model(&models.Quote{}).Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return PurgeQuotes(tx, ids)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// StartTrashPurge purges quotes older than retention from the trash every
+// interval until ctx is cancelled
+func StartTrashPurge(ctx context.Context, db *gorm.DB, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := PurgeTrash(db, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d quotes from the trash", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"Qoute-backend/config"
+	"Qoute-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeTrash(t *testing.T) {
+	os.Setenv("DATABASE_DSN", ":memory:")
+	config.InitDB()
+	db := config.DB
+
+	kept := models.Quote{Content: "kept", Author: "a"}
+	trashed := models.Quote{Content: "trashed", Author: "a"}
+	db.Create(&kept)
+	db.Create(&trashed)
+	db.Create(&models.Vote{QuoteID: trashed.ID})
+	db.Create(&models.CollectionItem{CollectionID: 1, QuoteID: trashed.ID})
+	db.Delete(&trashed)
+
+	// Nothing is old enough yet
+	purged, err := PurgeTrash(db, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+
+	purged, err = PurgeTrash(db, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var quotes, votes, items int64
+	db.Unscoped().Model(&models.Quote{}).Count(&quotes)
+	db.Model(&models.Vote{}).Count(&votes)
+	db.Model(&models.CollectionItem{}).Count(&items)
+	assert.EqualValues(t, 1, quotes)
+	assert.Zero(t, votes)
+	assert.Zero(t, items)
+}
@@ -0,0 +1,119 @@
+// Package langdetect guesses the language of short texts such as quotes.
+//
+// Texts in a distinctive script are identified by their script. Latin
+// texts are scored against lists of very common words, which is reliable
+// enough for sentences but not for single words.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Undetermined is the BCP 47 code returned when no language is recognised
+const Undetermined = "und"
+
+// scripts maps unambiguous scripts to the language most likely written in it
+var scripts = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Thai, "th"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Arabic, "ar"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Cyrillic, "ru"},
+}
+
+// stopwords lists frequent words of languages written in the Latin script
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "of", "to", "in", "that", "it", "you", "not", "be", "are", "what", "with", "for", "we", "your", "have", "but", "who"},
+	"fr": {"le", "la", "les", "et", "est", "des", "un", "une", "que", "qui", "pas", "ne", "du", "pour", "dans", "vous", "nous", "ce", "sont", "il", "je", "suis", "donc", "mais", "tout"},
+	"es": {"el", "la", "los", "las", "y", "es", "que", "de", "un", "una", "no", "por", "con", "para", "del", "se", "lo", "su", "como", "más"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "ich", "sie", "es", "mit", "den", "sich", "auf", "wer", "wir", "auch", "nur"},
+	"it": {"il", "di", "che", "non", "un", "una", "per", "sono", "della", "gli", "le", "del", "con", "si", "chi", "come", "anche", "ma", "è", "nel"},
+	"pt": {"o", "os", "as", "que", "não", "um", "uma", "do", "da", "para", "com", "se", "é", "em", "mais", "mas", "quem", "você", "nos", "ao"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "van", "dat", "die", "ik", "je", "zijn", "wie", "met", "op", "wat", "ook", "maar", "er", "te"},
+	"la": {"est", "et", "non", "in", "ad", "qui", "quod", "sed", "cum", "ut", "nihil", "esse", "sunt", "per", "omnia", "vita", "quam", "enim", "ergo", "sum"},
+}
+
+var stopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(stopwords))
+	for language, words := range stopwords {
+		sets[language] = make(map[string]bool, len(words))
+		for _, word := range words {
+			sets[language][word] = true
+		}
+	}
+	return sets
+}()
+
+// Detect returns the ISO 639-1 code of the language text is most likely
+// written in, or Undetermined
+func Detect(text string) string {
+	// Count letters per distinctive script
+	counts := make([]int, len(scripts))
+	latin := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for i, script := range scripts {
+			if unicode.Is(script.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+
+	best, bestCount := -1, latin
+	for i, count := range counts {
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+	if best >= 0 {
+		language := scripts[best].language
+		// Japanese mixes kanji with kana, so any kana means Japanese
+		if language == "zh" && counts[1]+counts[2] > 0 {
+			return "ja"
+		}
+		return language
+	}
+
+	return detectLatin(text)
+}
+
+// detectLatin scores a Latin script text against the stopword lists
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+
+	scores := map[string]int{}
+	for _, word := range words {
+		for language, set := range stopwordSets {
+			if set[word] {
+				scores[language]++
+			}
+		}
+	}
+
+	best, bestScore := Undetermined, 0
+	for language, score := range scores {
+		// Break ties alphabetically so results do not depend on map order
+		if score > bestScore || (score == bestScore && score > 0 && language < best) {
+			best, bestScore = language, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,24 @@
+package langdetect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	cases := map[string]string{
+		"The only true wisdom is in knowing you know nothing.":        "en",
+		"Je pense, donc je suis, et la vie est belle pour nous.":      "fr",
+		"La vida es sueño, y los sueños, sueños son.":                 "es",
+		"Was mich nicht umbringt, macht mich stärker, und das ist so": "de",
+		"Познай самого себя":                                          "ru",
+		"知之为知之，不知为不知，是知也":                                             "zh",
+		"古池や蛙飛び込む水の音":                                                 "ja",
+		"Γνῶθι σεαυτόν":                                               "el",
+		"12345":                                                       Undetermined,
+	}
+	for text, language := range cases {
+		assert.Equal(t, language, Detect(text), text)
+	}
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"time"
 
 	"Qoute-backend/config"
+	"Qoute-backend/events"
 	"Qoute-backend/handlers"
+	"Qoute-backend/jobs"
 	"Qoute-backend/middleware"
+	"Qoute-backend/models"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -20,9 +24,24 @@ func main() {
 		log.Println("No .env file found")
 	}
 
+	// Refuse to sign receipts and hash voters with empty keys
+	if err := config.CheckSecrets(); err != nil {
+		log.Fatal(err)
+	}
+
 	// Initialize database
 	config.InitDB()
 
+	// Purge old quotes from the trash in the background
+	go jobs.StartTrashPurge(context.Background(), config.DB, config.TrashRetention(), config.TrashPurgeInterval())
+
+	// Publish scheduled quotes in the background
+	bus := events.NewBus()
+	bus.Subscribe(events.QuotePublished, func(e events.Event) {
+		log.Printf("Published quote %d", e.QuoteID)
+	})
+	go jobs.StartPublisher(context.Background(), config.DB, bus, config.PublishInterval())
+
 	// Set default port
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -36,8 +55,8 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://127.0.0.1:3000", "http://localhost:5173", "http://127.0.0.1:5173", "https://quote-frontend-zeta.vercel.app"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Requested-With", "Accept"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Requested-With", "Accept", "Accept-Language", "If-Match", "If-None-Match"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Type", "Authorization", "ETag", "Content-Language"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -53,24 +72,157 @@ func main() {
 	router.POST("/register", handlers.Register)
 	router.POST("/login", handlers.Login)
 
-	// Initialize vote handler
+	// Initialize vote, report, collection, comment, related quote and feed handlers
 	voteHandler := handlers.NewVoteHandler(config.DB)
+	reportHandler := handlers.NewReportHandler(config.DB)
+	collectionHandler := handlers.NewCollectionHandler(config.DB)
+	commentHandler := handlers.NewCommentHandler(config.DB)
+	relatedHandler := handlers.NewRelatedHandler(config.DB)
+	feedHandler := handlers.NewFeedHandler(config.DB, relatedHandler)
+
+	// Public vote receipt verification
+	router.POST("/votes/verify", voteHandler.VerifyReceipt)
+
+	// Public read-only routes, personalised when a token is sent
+	publicQuotes := router.Group("/quotes")
+	publicQuotes.Use(middleware.OptionalAuthMiddleware())
+	{
+		publicQuotes.GET("/", handlers.GetQuotes)
+		publicQuotes.GET("/random", handlers.GetRandomQuote)
+		publicQuotes.GET("/daily", handlers.GetDailyQuote)
+		publicQuotes.GET("/:id", handlers.GetQuote)
+		publicQuotes.GET("/:id/translations", handlers.GetTranslations)
+		publicQuotes.GET("/:id/related", relatedHandler.GetRelated)
+		publicQuotes.GET("/:id/card.png", handlers.GetQuoteCardPNG)
+		publicQuotes.GET("/:id/card.svg", handlers.GetQuoteCardSVG)
+		publicQuotes.GET("/:id/comments", commentHandler.GetComments)
+		publicQuotes.GET("/:id/vote/count", voteHandler.GetVoteCount)
+	}
 
 	// Protected routes
 	quotes := router.Group("/quotes")
 	quotes.Use(middleware.AuthMiddleware())
 	{
 		quotes.POST("/", handlers.CreateQuote)
-		quotes.GET("/", handlers.GetQuotes)
-		quotes.GET("/:id", handlers.GetQuote)
+		quotes.POST("/import", handlers.ImportQuotes)
 		quotes.PUT("/:id", handlers.UpdateQuote)
+		quotes.PATCH("/:id", handlers.PatchQuote)
 		quotes.DELETE("/:id", handlers.DeleteQuote)
 
+		// Trash routes
+		quotes.GET("/trash", handlers.GetTrash)
+		quotes.POST("/:id/restore", handlers.RestoreQuote)
+
 		// Vote routes
 		quotes.POST("/:id/vote", voteHandler.CreateVote)
 		quotes.DELETE("/:id/vote", voteHandler.DeleteVote)
-		quotes.GET("/:id/vote/count", voteHandler.GetVoteCount)
 		quotes.GET("/:id/vote/check", voteHandler.CheckUserVote)
+
+		// Report routes
+		quotes.POST("/:id/report", reportHandler.CreateReport)
+
+		// Comment routes
+		quotes.POST("/:id/comments", commentHandler.CreateComment)
+
+		// Favourite routes
+		quotes.POST("/:id/favourite", collectionHandler.AddFavourite)
+		quotes.DELETE("/:id/favourite", collectionHandler.RemoveFavourite)
+	}
+
+	// Admin routes
+	adminQuotes := router.Group("/quotes")
+	adminQuotes.Use(middleware.AuthMiddleware(), middleware.RequireRole(config.DB, models.RoleAdmin))
+	{
+		adminQuotes.PUT("/daily/:date", handlers.PinDailyQuote)
+		adminQuotes.DELETE("/daily/:date", handlers.UnpinDailyQuote)
+		adminQuotes.DELETE("/:id/permanent", handlers.PurgeQuote)
+	}
+
+	// Moderation routes
+	moderationHandler := handlers.NewModerationHandler(config.DB)
+	moderation := router.Group("/moderation")
+	moderation.Use(middleware.AuthMiddleware(), middleware.RequireRole(config.DB, models.RoleModerator, models.RoleAdmin))
+	{
+		moderation.GET("/queue", moderationHandler.GetQueue)
+		moderation.POST("/quotes/:id/approve", moderationHandler.ApproveQuote)
+		moderation.POST("/quotes/:id/reject", moderationHandler.RejectQuote)
+		moderation.PUT("/quotes/:id/verification", moderationHandler.VerifyCitation)
+		moderation.GET("/reports", reportHandler.GetReports)
+		moderation.POST("/reports/:id/resolve", reportHandler.ResolveReport)
+	}
+
+	comments := router.Group("/comments")
+	comments.Use(middleware.AuthMiddleware())
+	{
+		comments.PUT("/:id", commentHandler.UpdateComment)
+		comments.DELETE("/:id", commentHandler.DeleteComment)
+	}
+
+	// Collection routes
+	publicCollections := router.Group("/")
+	publicCollections.Use(middleware.OptionalAuthMiddleware())
+	{
+		publicCollections.GET("/collections/:id", collectionHandler.GetCollection)
+		publicCollections.GET("/collections/shared/:token", collectionHandler.GetSharedCollection)
+		publicCollections.GET("/users/:id/collections", collectionHandler.GetUserCollections)
+	}
+
+	collections := router.Group("/collections")
+	collections.Use(middleware.AuthMiddleware())
+	{
+		collections.GET("/", collectionHandler.GetMyCollections)
+		collections.POST("/", collectionHandler.CreateCollection)
+		collections.PUT("/:id", collectionHandler.UpdateCollection)
+		collections.DELETE("/:id", collectionHandler.DeleteCollection)
+		collections.POST("/:id/items", collectionHandler.AddCollectionItem)
+		collections.PUT("/:id/items", collectionHandler.ReorderCollection)
+		collections.DELETE("/:id/items/:quote_id", collectionHandler.RemoveCollectionItem)
+	}
+
+	// Personal feed and followed authors
+	me := router.Group("/me")
+	me.Use(middleware.AuthMiddleware())
+	{
+		me.GET("/feed", feedHandler.GetFeed)
+		me.GET("/follows", feedHandler.GetFollows)
+		me.POST("/follows", feedHandler.FollowAuthor)
+		me.DELETE("/follows/:author", feedHandler.UnfollowAuthor)
+	}
+
+	// Export routes
+	exports := router.Group("/export")
+	exports.Use(middleware.OptionalAuthMiddleware())
+	{
+		exports.GET("/quotes", handlers.ExportQuotes)
+	}
+
+	adminExports := router.Group("/export")
+	adminExports.Use(middleware.AuthMiddleware(), middleware.RequireRole(config.DB, models.RoleAdmin))
+	{
+		adminExports.GET("/votes", handlers.ExportVotes)
+	}
+
+	// HTML share pages of public quotes, and oEmbed for embedding them
+	router.GET("/q/:id", handlers.GetSharePage)
+	router.GET("/oembed", handlers.GetOEmbed)
+
+	// Atom and RSS feeds of public quotes
+	feeds := router.Group("/feeds")
+	{
+		feeds.GET("/quotes.atom", handlers.GetQuotesAtom)
+		feeds.GET("/quotes.rss", handlers.GetQuotesRSS)
+		feeds.GET("/top.atom", handlers.GetTopQuotesAtom)
+		feeds.GET("/top.rss", handlers.GetTopQuotesRSS)
+	}
+
+	// Quote battle routes
+	battleHandler := handlers.NewBattleHandler(config.DB)
+	battles := router.Group("/battles")
+	battles.Use(middleware.AuthMiddleware())
+	{
+		battles.GET("/next", battleHandler.NextBattle)
+		battles.GET("/rankings", battleHandler.GetRankings)
+		battles.POST("/:id", battleHandler.RecordBattle)
 	}
 
 	// Start server
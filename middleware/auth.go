@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"os"
 	"strings"
@@ -10,6 +11,53 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var (
+	errAuthFormat   = errors.New("authorization header is not a bearer token")
+	errInvalidToken = errors.New("invalid token")
+)
+
+// parseBearerToken validates an Authorization header and returns the ID of
+// the user the token was issued to
+func parseBearerToken(authHeader string) (uint, error) {
+	// Check if the header has the Bearer prefix
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return 0, errAuthFormat
+	}
+
+	// Parse and validate the token
+	token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
+		// Validate the signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, errInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, errInvalidToken
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, errInvalidToken
+	}
+	return uint(userID), nil
+}
+
+// abortWithAuthError rejects the request with the message for err
+func abortWithAuthError(c *gin.Context, err error) {
+	message := "Invalid token"
+	if errors.Is(err, errAuthFormat) {
+		message = "Authorization header format must be Bearer {token}"
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	c.Abort()
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the Authorization header
@@ -20,44 +68,39 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Check if the header has the Bearer prefix
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
-			c.Abort()
+		userID, err := parseBearerToken(authHeader)
+		if err != nil {
+			abortWithAuthError(c, err)
 			return
 		}
 
-		// Get the token
-		tokenString := parts[1]
-
-		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		// Set the user ID in the context
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+// OptionalAuthMiddleware lets anonymous requests through and sets the user ID
+// when a token is sent. An invalid token is still rejected so clients notice
+// an expired session instead of silently browsing anonymously.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
 			return
 		}
 
-		// Check if the token is valid
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Set the user ID in the context
-			c.Set("user_id", uint(claims["user_id"].(float64)))
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+		userID, err := parseBearerToken(authHeader)
+		if err != nil {
+			abortWithAuthError(c, err)
 			return
 		}
+
+		c.Set("user_id", userID)
+		c.Next()
 	}
-} 
+}
 
 // GenerateJWT creates a new JWT token for a given user ID
 func GenerateJWT(userID uint) (string, error) {
@@ -68,4 +111,4 @@ func GenerateJWT(userID uint) (string, error) {
 
 	// Sign the token with our secret
 	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
-}
\ No newline at end of file
+}
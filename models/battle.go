@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Battle is a head-to-head match between two quotes judged by one user.
+// QuoteAID is always the lower quote ID so each pair has a single key.
+type Battle struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_battles_user_pair"`
+	QuoteAID  uint       `json:"quote_a_id" gorm:"not null;uniqueIndex:idx_battles_user_pair"`
+	QuoteBID  uint       `json:"quote_b_id" gorm:"not null;uniqueIndex:idx_battles_user_pair"`
+	WinnerID  *uint      `json:"winner_id"`
+	DecidedAt *time.Time `json:"decided_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	QuoteA    Quote      `json:"-" gorm:"foreignKey:QuoteAID"`
+	QuoteB    Quote      `json:"-" gorm:"foreignKey:QuoteBID"`
+}
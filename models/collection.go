@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Collection visibilities
+const (
+	CollectionVisibilityPublic   = "public"
+	CollectionVisibilityPrivate  = "private"
+	CollectionVisibilityUnlisted = "unlisted" // Readable by anyone with the share token
+)
+
+// DefaultCollectionTitle is the title of the collection every user gets
+const DefaultCollectionTitle = "Favourites"
+
+// Collection is a user's ordered list of quotes
+type Collection struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index;uniqueIndex:idx_collections_user_default,where:is_default"` // One default per user
+	Title       string    `json:"title" gorm:"not null"`
+	Description string    `json:"description"`
+	Visibility  string    `json:"visibility" gorm:"not null;default:private"`
+	IsDefault   bool      `json:"is_default" gorm:"not null;default:false"`
+	ShareToken  string    `json:"share_token,omitempty" gorm:"uniqueIndex"` // Only shown to the owner
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CollectionItem places a quote in a collection. Items are ordered by
+// Position, lowest first.
+type CollectionItem struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	CollectionID uint      `json:"collection_id" gorm:"not null;uniqueIndex:idx_collection_items_collection_quote"`
+	QuoteID      uint      `json:"quote_id" gorm:"not null;uniqueIndex:idx_collection_items_collection_quote;index"`
+	Position     int       `json:"position" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
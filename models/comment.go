@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeletedCommentPlaceholder replaces the content of deleted comments that
+// are still shown because they have replies
+const DeletedCommentPlaceholder = "[deleted]"
+
+// Comment is a user's comment on a quote. Replies have a ParentID and only
+// top level comments can be replied to.
+type Comment struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	QuoteID   uint           `json:"quote_id" gorm:"not null;index"`
+	UserID    uint           `json:"user_id,omitempty" gorm:"not null;index"` // Left out for deleted comments
+	ParentID  *uint          `json:"parent_id,omitempty" gorm:"index"`
+	Content   string         `json:"content" gorm:"not null"`
+	EditedAt  *time.Time     `json:"edited_at,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	User      *User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
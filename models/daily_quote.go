@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// DailyQuote pins a quote as the quote of the day for a calendar date
+type DailyQuote struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Date      string    `json:"date" gorm:"uniqueIndex;not null"` // YYYY-MM-DD
+	QuoteID   uint      `json:"quote_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuthorFollow is a user following an author, whose quotes then rank higher
+// in the user's feed. Authors are matched without regard to case.
+type AuthorFollow struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_author_follows_user_author_key"`
+	Author    string    `json:"author" gorm:"not null"`                                                      // With surrounding and repeated spaces removed
+	AuthorKey string    `json:"-" gorm:"not null;default:'';uniqueIndex:idx_author_follows_user_author_key"` // AuthorKey of Author
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BeforeSave keeps the author key in sync with the author
+func (f *AuthorFollow) BeforeSave(tx *gorm.DB) error {
+	f.AuthorKey = AuthorKey(f.Author)
+	return nil
+}
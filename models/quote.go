@@ -1,17 +1,76 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Quote moderation statuses
+const (
+	QuoteStatusPending  = "pending"
+	QuoteStatusApproved = "approved"
+	QuoteStatusRejected = "rejected"
+)
+
+// Citation verification statuses
+const (
+	CitationUnverified = "unverified"
+	CitationVerified   = "verified"
+	CitationDisputed   = "disputed"
+)
+
+// Citation describes where a quote comes from
+type Citation struct {
+	WorkTitle    string `json:"work_title,omitempty"`
+	Year         *int   `json:"year,omitempty"`
+	Page         string `json:"page,omitempty"`
+	URL          string `json:"url,omitempty"`
+	License      string `json:"license,omitempty"`
+	Verification string `json:"verification" gorm:"not null;default:unverified;index"`
+}
+
 type Quote struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Content   string         `json:"content" gorm:"not null"`
-	Author    string         `json:"author" gorm:"not null"`
-	Votes     []Vote         `json:"votes,omitempty" gorm:"foreignKey:QuoteID"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Content         string         `json:"content" gorm:"not null"`
+	Author          string         `json:"author" gorm:"not null"`
+	AuthorKey       string         `json:"-" gorm:"not null;default:'';index"` // Normalized author, kept in sync by BeforeSave
+	Source          Citation       `json:"source" gorm:"embedded;embeddedPrefix:source_"`
+	Language        string         `json:"language" gorm:"not null;default:und;index"` // BCP 47 tag, detected when omitted
+	TranslationOf   *uint          `json:"translation_of,omitempty" gorm:"index"`      // Original quote of a translation
+	UserID          *uint          `json:"user_id,omitempty" gorm:"index"`             // Submitter, empty for quotes created before moderation
+	Status          string         `json:"status" gorm:"not null;default:approved;index"`
+	RejectionReason string         `json:"rejection_reason,omitempty"`
+	ModeratedBy     *uint          `json:"moderated_by,omitempty"`
+	ModeratedAt     *time.Time     `json:"moderated_at,omitempty"`
+	Hidden          bool           `json:"hidden" gorm:"not null;default:false"` // Hidden after too many reports
+	Votes           []Vote         `json:"votes,omitempty" gorm:"foreignKey:QuoteID"`
+	Comments        []Comment      `json:"-" gorm:"foreignKey:QuoteID"`
+	Rating          float64        `json:"rating" gorm:"not null;default:1500"` // Elo rating from quote battles
+	Battles         int            `json:"battles" gorm:"not null;default:0"`   // Number of decided battles
+	Version         uint           `json:"version" gorm:"not null;default:1"`   // Bumped on every change, used for ETags
+	PublishAt       *time.Time     `json:"publish_at,omitempty" gorm:"index"`   // Scheduled publication, empty to publish right away
+	PublishedAt     *time.Time     `json:"published_at,omitempty" gorm:"index"` // When the publisher saw the quote go live
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// Published reports whether the quote's scheduled publication time, if any,
+// has passed
+func (q Quote) Published(now time.Time) bool {
+	return q.PublishAt == nil || !q.PublishAt.After(now)
+}
+
+// AuthorKey normalizes an author name for comparisons, ignoring case and
+// spacing. Unlike SQL LOWER it folds the case of any script.
+func AuthorKey(author string) string {
+	return strings.ToLower(strings.Join(strings.Fields(author), " "))
+}
+
+// BeforeSave keeps the author key in sync with the author
+func (q *Quote) BeforeSave(tx *gorm.DB) error {
+	q.AuthorKey = AuthorKey(q.Author)
+	return nil
 }
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Report reasons
+const (
+	ReportReasonMisattributed = "misattributed"
+	ReportReasonOffensive     = "offensive"
+	ReportReasonSpam          = "spam"
+	ReportReasonDuplicate     = "duplicate"
+	ReportReasonOther         = "other"
+)
+
+// Report statuses
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+// Report is a user's flag on a quote, triaged by moderators
+type Report struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	QuoteID    uint       `json:"quote_id" gorm:"not null;uniqueIndex:idx_reports_quote_user"`
+	UserID     uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_reports_quote_user;index"`
+	Reason     string     `json:"reason" gorm:"not null"`
+	Details    string     `json:"details,omitempty"`
+	Status     string     `json:"status" gorm:"not null;default:open;index"`
+	Resolution string     `json:"resolution,omitempty"`
+	ResolvedBy *uint      `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Quote      *Quote     `json:"quote,omitempty" gorm:"foreignKey:QuoteID"`
+}
@@ -4,10 +4,13 @@ import "time"
 
 type Vote struct {
     ID        uint      `json:"id" gorm:"primaryKey"`
-    UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex"`
+    // UserID is left empty when votes are stored anonymously
+    UserID    *uint     `json:"user_id,omitempty" gorm:"uniqueIndex"`
+    // VoterHash is the salted user hash enforcing one vote per user
+    VoterHash *string   `json:"-" gorm:"uniqueIndex"`
     QuoteID   uint      `json:"quote_id" gorm:"not null"`
     CreatedAt time.Time `json:"created_at"`
     UpdatedAt time.Time `json:"updated_at"`
-    User      User      `json:"user" gorm:"foreignKey:UserID"`
+    User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
     Quote     Quote     `json:"quote" gorm:"foreignKey:QuoteID"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,59 @@
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decode(t *testing.T, document string) interface{} {
+	var value interface{}
+	assert.NoError(t, json.Unmarshal([]byte(document), &value))
+	return value
+}
+
+func TestMerge(t *testing.T) {
+	// Example from RFC 7396 section 3
+	target := decode(t, `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`)
+	patch := decode(t, `{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`)
+	expected := decode(t, `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`)
+	assert.Equal(t, expected, Merge(target, patch))
+
+	assert.Equal(t, "replaced", Merge(decode(t, `{"a":1}`), "replaced"))
+}
+
+func TestApply(t *testing.T) {
+	var operations []Operation
+	assert.NoError(t, json.Unmarshal([]byte(`[
+		{"op":"test","path":"/a~1b","value":1},
+		{"op":"replace","path":"/a~1b","value":2},
+		{"op":"add","path":"/list/1","value":"x"},
+		{"op":"add","path":"/list/-","value":"z"},
+		{"op":"remove","path":"/list/0"},
+		{"op":"copy","from":"/nested","path":"/copied"},
+		{"op":"move","from":"/nested/value","path":"/moved"}
+	]`), &operations))
+
+	doc := decode(t, `{"a/b":1,"list":["w","y"],"nested":{"value":true}}`)
+	result, err := Apply(doc, operations)
+	assert.NoError(t, err)
+	assert.Equal(t, decode(t, `{"a/b":2,"list":["x","y","z"],"nested":{},"copied":{"value":true},"moved":true}`), result)
+}
+
+func TestApplyErrors(t *testing.T) {
+	cases := []string{
+		`[{"op":"test","path":"/a","value":2}]`,
+		`[{"op":"replace","path":"/missing","value":2}]`,
+		`[{"op":"remove","path":"/list/5"}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+		`[{"op":"move","from":"/obj","path":"/obj/inner"}]`,
+		`[{"op":"frobnicate","path":"/a"}]`,
+	}
+	for _, c := range cases {
+		var operations []Operation
+		assert.NoError(t, json.Unmarshal([]byte(c), &operations))
+		_, err := Apply(decode(t, `{"a":1,"list":[1],"obj":{}}`), operations)
+		assert.Error(t, err, c)
+	}
+}
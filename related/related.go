@@ -0,0 +1,251 @@
+// Package related finds quotes similar to a quote. An in-memory TF-IDF index
+// over quote text finds similar wording, and Signals combines it with other
+// evidence such as a shared author into a score and reasons.
+package related
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Document is a quote as the index sees it
+type Document struct {
+	ID       uint
+	Version  uint // Used by Sync to find changed quotes
+	Text     string
+	Author   string
+	Language string
+}
+
+// Similarity is how a quote resembles another in the index
+type Similarity struct {
+	Text         float64  // Cosine similarity of the TF-IDF vectors, 0 to 1
+	SharedTerms  []string // Terms contributing most to Text
+	SameAuthor   bool
+	SameLanguage bool
+}
+
+type document struct {
+	Document
+	terms  map[string]int
+	author string
+}
+
+// Index is a TF-IDF index of quotes, updated one quote at a time. It is safe
+// for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[uint]*document
+	postings map[string]map[uint]int // Term to term count by document
+	authors  map[string]map[uint]bool
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[uint]*document{},
+		postings: map[string]map[uint]int{},
+		authors:  map[string]map[uint]bool{},
+	}
+}
+
+// stopwords are common English words that say nothing about a quote
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`a about all also am an and any are as at be because been but by can
+		could did do does for from had has have he her him his how i if in into is it its just me more
+		most my no not of on one only or our out she so some than that the their them then there these
+		they this those to too up us was we were what when which who will with would you your`) {
+		stopwords[word] = true
+	}
+}
+
+// terms splits text into lowercase words, leaving out stopwords and single
+// letters
+func terms(text string) map[string]int {
+	counts := map[string]int{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	for _, word := range words {
+		word = strings.TrimSuffix(strings.Trim(word, "'"), "'s")
+		if len([]rune(word)) < 2 || stopwords[word] {
+			continue
+		}
+		counts[word]++
+	}
+	return counts
+}
+
+func normalizeAuthor(author string) string {
+	return strings.ToLower(strings.Join(strings.Fields(author), " "))
+}
+
+// Add indexes a quote, replacing an earlier version of it
+func (ix *Index) Add(doc Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(doc.ID)
+
+	d := &document{Document: doc, terms: terms(doc.Text), author: normalizeAuthor(doc.Author)}
+	ix.docs[doc.ID] = d
+	for term, count := range d.terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[uint]int{}
+		}
+		ix.postings[term][doc.ID] = count
+	}
+	if d.author != "" {
+		if ix.authors[d.author] == nil {
+			ix.authors[d.author] = map[uint]bool{}
+		}
+		ix.authors[d.author][doc.ID] = true
+	}
+}
+
+// Remove drops a quote from the index
+func (ix *Index) Remove(id uint) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id uint) {
+	d, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for term := range d.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.authors[d.author], id)
+	if len(ix.authors[d.author]) == 0 {
+		delete(ix.authors, d.author)
+	}
+	delete(ix.docs, id)
+}
+
+// Sync compares the index with the current quote versions by ID. Quotes
+// that no longer exist are removed, and the IDs of quotes that are new or
+// changed are returned so the caller can Add them.
+func (ix *Index) Sync(versions map[uint]uint) []uint {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for id := range ix.docs {
+		if _, ok := versions[id]; !ok {
+			ix.remove(id)
+		}
+	}
+	var stale []uint
+	for id, version := range versions {
+		if d, ok := ix.docs[id]; !ok || d.Version != version {
+			stale = append(stale, id)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i] < stale[j] })
+	return stale
+}
+
+// Len returns the number of indexed quotes
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// weight is the TF-IDF weight of a term occurring count times in a document
+func (ix *Index) weight(term string, count int) float64 {
+	idf := math.Log(1 + float64(len(ix.docs))/float64(len(ix.postings[term])))
+	return (1 + math.Log(float64(count))) * idf
+}
+
+func (ix *Index) norm(d *document) float64 {
+	sum := 0.0
+	for term, count := range d.terms {
+		w := ix.weight(term, count)
+		sum += w * w
+	}
+	return math.Sqrt(sum)
+}
+
+// maxSharedTerms is how many shared terms a Similarity lists
+const maxSharedTerms = 3
+
+// Similar returns the quotes sharing words or the author with a quote, by
+// ID. It is empty when the quote is not indexed.
+func (ix *Index) Similar(id uint) map[uint]Similarity {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	target, ok := ix.docs[id]
+	if !ok {
+		return map[uint]Similarity{}
+	}
+
+	// Dot products with every document sharing a term, by term
+	contributions := map[uint]map[string]float64{}
+	for term, count := range target.terms {
+		w := ix.weight(term, count)
+		for other, otherCount := range ix.postings[term] {
+			if other == id {
+				continue
+			}
+			if contributions[other] == nil {
+				contributions[other] = map[string]float64{}
+			}
+			contributions[other][term] = w * ix.weight(term, otherCount)
+		}
+	}
+
+	similar := map[uint]Similarity{}
+	targetNorm := ix.norm(target)
+	for other, byTerm := range contributions {
+		d := ix.docs[other]
+		dot := 0.0
+		shared := make([]string, 0, len(byTerm))
+		for term, contribution := range byTerm {
+			dot += contribution
+			shared = append(shared, term)
+		}
+		sort.Slice(shared, func(i, j int) bool {
+			if byTerm[shared[i]] != byTerm[shared[j]] {
+				return byTerm[shared[i]] > byTerm[shared[j]]
+			}
+			return shared[i] < shared[j]
+		})
+		if len(shared) > maxSharedTerms {
+			shared = shared[:maxSharedTerms]
+		}
+		similarity := Similarity{SharedTerms: shared, SameLanguage: sameLanguage(target.Language, d.Language)}
+		if norm := targetNorm * ix.norm(d); norm > 0 {
+			similarity.Text = math.Min(dot/norm, 1)
+		}
+		similar[other] = similarity
+	}
+
+	for other := range ix.authors[target.author] {
+		if other == id {
+			continue
+		}
+		similarity := similar[other]
+		similarity.SameAuthor = true
+		similarity.SameLanguage = sameLanguage(target.Language, ix.docs[other].Language)
+		similar[other] = similarity
+	}
+	return similar
+}
+
+// sameLanguage reports whether two language tags share their primary
+// language. Undetermined languages never match.
+func sameLanguage(a, b string) bool {
+	primary := func(tag string) string {
+		return strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+	}
+	return a != "" && a != "und" && primary(a) == primary(b)
+}
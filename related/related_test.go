@@ -0,0 +1,73 @@
+package related
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testIndex() *Index {
+	ix := NewIndex()
+	ix.Add(Document{ID: 1, Version: 1, Text: "The only true wisdom is in knowing you know nothing.", Author: "Socrates", Language: "en"})
+	ix.Add(Document{ID: 2, Version: 1, Text: "Knowing yourself is the beginning of all wisdom.", Author: "Aristotle", Language: "en"})
+	ix.Add(Document{ID: 3, Version: 1, Text: "An unexamined life is not worth living.", Author: "socrates ", Language: "en-GB"})
+	ix.Add(Document{ID: 4, Version: 1, Text: "Be the change that you wish to see in the world.", Author: "Mahatma Gandhi", Language: "en"})
+	return ix
+}
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, map[string]int{"wisdom": 2, "socrates": 1, "don't": 1}, terms("The wisdom of Socrates's wisdom? Don't!"))
+}
+
+func TestSimilar(t *testing.T) {
+	ix := testIndex()
+	similar := ix.Similar(1)
+
+	// Shared words and a shared author count, unrelated quotes are left out
+	assert.Greater(t, similar[2].Text, 0.2)
+	assert.ElementsMatch(t, []string{"knowing", "wisdom"}, similar[2].SharedTerms)
+	assert.False(t, similar[2].SameAuthor)
+	assert.True(t, similar[3].SameAuthor)
+	assert.True(t, similar[3].SameLanguage)
+	assert.Zero(t, similar[3].Text)
+	assert.NotContains(t, similar, uint(4))
+	assert.NotContains(t, similar, uint(1))
+
+	// Identical text is fully similar
+	ix.Add(Document{ID: 5, Version: 1, Text: "The only true wisdom is in knowing you know nothing.", Author: "Anonymous"})
+	assert.InDelta(t, 1.0, ix.Similar(1)[5].Text, 1e-9)
+	assert.Empty(t, ix.Similar(99))
+}
+
+func TestIncrementalUpdates(t *testing.T) {
+	ix := testIndex()
+
+	// Editing a quote replaces its terms
+	ix.Add(Document{ID: 4, Version: 2, Text: "Wisdom begins in wonder.", Author: "Socrates"})
+	similar := ix.Similar(1)
+	assert.Contains(t, similar, uint(4))
+	assert.True(t, similar[4].SameAuthor)
+	assert.Equal(t, 4, ix.Len())
+
+	ix.Remove(2)
+	assert.NotContains(t, ix.Similar(1), uint(2))
+	assert.NotContains(t, ix.postings, "beginning", "terms only the removed quote had are dropped")
+
+	// Sync drops missing quotes and reports new and changed ones
+	stale := ix.Sync(map[uint]uint{1: 1, 3: 2, 4: 2, 7: 1})
+	assert.Equal(t, []uint{3, 7}, stale)
+	assert.Equal(t, 3, ix.Len())
+}
+
+func TestSignals(t *testing.T) {
+	text := Signals{Similarity: Similarity{Text: 0.6, SharedTerms: []string{"wisdom"}}}
+	author := Signals{Similarity: Similarity{SameAuthor: true, SameLanguage: true}}
+	liked := Signals{CoLikes: 3}
+
+	assert.Greater(t, text.Score(), author.Score())
+	assert.Greater(t, author.Score(), liked.Score())
+	assert.Less(t, Signals{Similarity: Similarity{SameLanguage: true}}.Score(), MinScore)
+
+	both := Signals{Similarity: Similarity{Text: 0.2, SharedTerms: []string{"life"}, SameAuthor: true}, CoLikes: 1}
+	assert.Equal(t, []string{"Same author", "Similar wording: life", "Liked by someone who liked this quote"}, both.Reasons())
+}
@@ -0,0 +1,68 @@
+package related
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Weights of the evidence that two quotes are related
+const (
+	textWeight     = 1.0
+	authorWeight   = 0.4
+	coLikeWeight   = 0.3
+	languageWeight = 0.05
+)
+
+// MinScore is the score below which quotes are not considered related
+const MinScore = 0.1
+
+// Signals is the evidence that a quote is related to another
+type Signals struct {
+	Similarity
+	CoLikes int // Users who liked both quotes
+}
+
+// Score combines the signals into a single relevance score. Shared words
+// count most, then a shared author, then people liking both. A shared
+// language only breaks ties.
+func (s Signals) Score() float64 {
+	score := textWeight * s.Text
+	if s.SameAuthor {
+		score += authorWeight
+	}
+	if s.CoLikes > 0 {
+		score += coLikeWeight * math.Min(math.Log2(1+float64(s.CoLikes))/3, 1)
+	}
+	if s.SameLanguage {
+		score += languageWeight
+	}
+	return score
+}
+
+// Reasons explains the score in words, strongest signal first
+func (s Signals) Reasons() []string {
+	type reason struct {
+		weight float64
+		text   string
+	}
+	var reasons []reason
+	if s.Text >= MinScore/2 && len(s.SharedTerms) > 0 {
+		reasons = append(reasons, reason{textWeight * s.Text, "Similar wording: " + strings.Join(s.SharedTerms, ", ")})
+	}
+	if s.SameAuthor {
+		reasons = append(reasons, reason{authorWeight, "Same author"})
+	}
+	if s.CoLikes == 1 {
+		reasons = append(reasons, reason{coLikeWeight / 3, "Liked by someone who liked this quote"})
+	} else if s.CoLikes > 1 {
+		reasons = append(reasons, reason{coLikeWeight / 3 * math.Log2(1+float64(s.CoLikes)), "Liked by people who liked this quote"})
+	}
+	sort.SliceStable(reasons, func(i, j int) bool { return reasons[i].weight > reasons[j].weight })
+
+	texts := make([]string, len(reasons))
+	for i, r := range reasons {
+		texts[i] = r.text
+	}
+	return texts
+}